@@ -18,7 +18,7 @@ func messageEvent(msg e22.Message, err error) {
 		return
 	}
 	log.Printf("DATA: %s", string(msg.Payload))
-	log.Printf("RSSI [-%d dBm]", msg.RSSI)
+	log.Printf("RSSI [%d dBm]", msg.RSSIdBm())
 }
 
 func main() {
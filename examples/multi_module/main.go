@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/mbalug7/go-ebyte-lora/pkg/common"
+	"github.com/mbalug7/go-ebyte-lora/pkg/e22"
+	"github.com/mbalug7/go-ebyte-lora/pkg/hal"
+)
+
+// radio wiring of one module
+type radio struct {
+	name     string
+	m0       int
+	m1       int
+	aux      int
+	tty      string
+	gpioChip string
+}
+
+// every module has its own serial port and GPIO lines, handlers and modules don't share any state,
+// so they can be used from separate goroutines
+var radios = []radio{
+	{name: "radio0", m0: 23, m1: 24, aux: 25, tty: "/dev/ttyS0", gpioChip: "gpiochip0"},
+	{name: "radio1", m0: 5, m1: 6, aux: 13, tty: "/dev/ttyAMA1", gpioChip: "gpiochip0"},
+}
+
+// openRadio creates hardware handler and module for the given wiring
+func openRadio(r radio) (*common.HWHandler, *e22.Module, error) {
+	hw, err := common.NewHWHandler(r.m0, r.m1, r.aux, r.tty, r.gpioChip)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", r.name, err)
+	}
+	module, err := e22.NewModule(hw, func(msg e22.Message, err error) {
+		if err != nil {
+			log.Printf("%s: message event error: %s", r.name, err)
+			return
+		}
+		log.Printf("%s: DATA: %s, RSSI [%d dBm]", r.name, string(msg.Payload), msg.RSSIdBm())
+	})
+	if err != nil {
+		hw.Close()
+		return nil, nil, fmt.Errorf("%s: %w", r.name, err)
+	}
+	err = hw.SetMode(hal.ModeNormal)
+	if err != nil {
+		module.Close()
+		hw.Close()
+		return nil, nil, fmt.Errorf("%s: %w", r.name, err)
+	}
+	return hw, module, nil
+}
+
+func main() {
+	handlers := make([]*common.HWHandler, 0, len(radios))
+	modules := make([]*e22.Module, 0, len(radios))
+	for _, r := range radios {
+		hw, module, err := openRadio(r)
+		if err != nil {
+			log.Fatal(err)
+		}
+		handlers = append(handlers, hw)
+		modules = append(modules, module)
+	}
+
+	// send from both modules at the same time, each send waits only for its own module
+	var wg sync.WaitGroup
+	for i, module := range modules {
+		wg.Add(1)
+		go func(name string, module *e22.Module) {
+			defer wg.Done()
+			err := module.SendMessage("hello from " + name)
+			if err != nil {
+				log.Printf("%s: failed to send data: %s", name, err)
+			}
+		}(radios[i].name, module)
+	}
+	wg.Wait()
+
+	// wait for keyboard signal interrupt
+	signalInterruptChan := make(chan os.Signal, 1)
+	signal.Notify(signalInterruptChan, os.Interrupt, syscall.SIGTERM)
+	<-signalInterruptChan
+	for i, module := range modules {
+		module.Close()
+		err := handlers[i].Close()
+		if err != nil {
+			log.Printf("%s: failed to close communication with the module: %s", radios[i].name, err)
+		}
+	}
+}
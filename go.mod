@@ -3,8 +3,7 @@ module github.com/mbalug7/go-ebyte-lora
 go 1.16
 
 require (
-	github.com/mazen160/go-random v0.0.0-20210308102632-d2b501c85c03
 	github.com/tarm/serial v0.0.0-20180830185346-98f6abe2eb07
 	github.com/warthog618/gpiod v0.7.1
-	golang.org/x/sys v0.0.0-20211116061358-0a5406a5449c // indirect
+	golang.org/x/sys v0.0.0-20211116061358-0a5406a5449c
 )
@@ -0,0 +1,10 @@
+//go:build auxinject
+// +build auxinject
+
+package common
+
+// InjectAuxEdge simulates AUX edge, it is handled the same way as an edge received from the GPIO line.
+// Available only with the auxinject build tag, use it to drive write, mode switch and read coordination without real hardware
+func (obj *HWHandler) InjectAuxEdge(rising bool) {
+	obj.handleAuxEdge(rising)
+}
@@ -0,0 +1,176 @@
+//go:build auxinject
+// +build auxinject
+
+package common
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mbalug7/go-ebyte-lora/pkg/hal"
+)
+
+func TestWriteSerialWaitsForBusyAUX(t *testing.T) {
+	handler, port, aux := newTestHandler(t, false)
+	aux.set(0)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- handler.WriteSerial([]byte("hello"))
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("write returned while AUX is busy, err: %v", err)
+	case <-port.written:
+		t.Fatal("data is written while AUX is busy")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	aux.set(1)
+	handler.InjectAuxEdge(true)
+	waitAuxAction(t, handler, actionWrite)
+	handler.InjectAuxEdge(false)
+	handler.InjectAuxEdge(true)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("write didn't complete after AUX went idle")
+	}
+	if string(port.sent()) != "hello" {
+		t.Fatalf("sent %q, expected %q", port.sent(), "hello")
+	}
+}
+
+func TestWriteSerialCompletesOnRisingEdge(t *testing.T) {
+	handler, _, _ := newTestHandler(t, false)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- handler.WriteSerial([]byte{0x01, 0x02})
+	}()
+	waitAuxAction(t, handler, actionWrite)
+	select {
+	case err := <-done:
+		t.Fatalf("write returned before the rising edge, err: %v", err)
+	default:
+	}
+
+	handler.InjectAuxEdge(true)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("write didn't complete on the rising edge")
+	}
+	waitAuxAction(t, handler, actionRead)
+}
+
+func TestTimedOutAUXWaiterIsRemoved(t *testing.T) {
+	handler, _, aux := newTestHandler(t, false)
+	aux.set(0)
+
+	err := handler.WaitAUXIdle(10 * time.Millisecond)
+	if !errors.Is(err, hal.ErrChipBusyTimeout) {
+		t.Fatalf("expected ErrChipBusyTimeout, got: %v", err)
+	}
+	handler.muAuxDone.Lock()
+	waiters := len(handler.auxBusyWaitGroup)
+	handler.muAuxDone.Unlock()
+	if waiters != 0 {
+		t.Fatalf("%d waiters left in the AUX busy group after timeout", waiters)
+	}
+
+	// edge that comes after the timeout has no one to notify
+	handler.InjectAuxEdge(true)
+}
+
+func TestCanceledWriteResetsAUXAction(t *testing.T) {
+	handler, _, _ := newTestHandler(t, false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- handler.WriteSerialContext(ctx, []byte{0x01})
+	}()
+	waitAuxAction(t, handler, actionWrite)
+	cancel()
+	err := <-done
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got: %v", err)
+	}
+	waitAuxAction(t, handler, actionRead)
+	if len(handler.writeDone) != 0 {
+		t.Fatal("write done signal is left after the canceled write")
+	}
+}
+
+func TestReceptionDuringWriteIsNotWriteDone(t *testing.T) {
+	handler, port, _ := newTestHandler(t, false)
+	received := make(chan []byte, 1)
+	handler.RegisterOnMessageCb(func(data []byte, err error) {
+		received <- data
+	})
+
+	done := make(chan error, 1)
+	go func() {
+		done <- handler.WriteSerial([]byte("ping"))
+	}()
+	// reception ends after the write reached the port, the module didn't start with the write yet
+	waitAuxAction(t, handler, actionWrite)
+	port.queue([]byte("pong"))
+	handler.InjectAuxEdge(true)
+	select {
+	case data := <-received:
+		if string(data) != "pong" {
+			t.Fatalf("received %q, expected %q", data, "pong")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("data received during the write is not delivered")
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("reception edge is taken as write done, err: %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// the module transmits the written data
+	handler.InjectAuxEdge(false)
+	handler.InjectAuxEdge(true)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("write didn't complete on the write done edge")
+	}
+}
+
+func TestWriteDoneEdgeWithEmptyBufferIsNotRead(t *testing.T) {
+	handler, port, _ := newTestHandler(t, false)
+	done := make(chan error, 1)
+	go func() {
+		done <- handler.WriteSerial([]byte("ping"))
+	}()
+	// falling edge is missed, the rising edge is the write done edge if nothing is buffered
+	waitAuxAction(t, handler, actionWrite)
+	handler.InjectAuxEdge(true)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("write didn't complete on the write done edge")
+	}
+	if port.readCount() != 0 {
+		t.Fatalf("serial port is read %d times on the write done edge with an empty buffer", port.readCount())
+	}
+}
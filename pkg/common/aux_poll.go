@@ -0,0 +1,29 @@
+package common
+
+// pollAux replaces AUX edge events, AUX line value is read every auxPollInterval and every change is handled as an edge
+func (obj *HWHandler) pollAux() {
+	defer close(obj.pollDone)
+	last, err := obj.auxLevel()
+	if err != nil {
+		last = 1
+	}
+	for {
+		select {
+		case <-obj.stopPoll:
+			return
+		case <-obj.clock.After(obj.auxPollInterval):
+		}
+		obj.muClosed.RLock()
+		if obj.closed {
+			obj.muClosed.RUnlock()
+			return
+		}
+		val, err := obj.auxLevel()
+		obj.muClosed.RUnlock()
+		if err != nil || val == last {
+			continue
+		}
+		last = val
+		obj.handleAuxEdge(val == 1)
+	}
+}
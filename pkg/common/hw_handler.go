@@ -1,22 +1,50 @@
 package common
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"sync"
 	"sync/atomic"
 	"time"
 
-	"github.com/mazen160/go-random"
 	"github.com/mbalug7/go-ebyte-lora/pkg/hal"
 	"github.com/tarm/serial"
 	"github.com/warthog618/gpiod"
+	"golang.org/x/sys/unix"
 )
 
+// auxIdleCheckTimeout time in which AUX must go idle after construction
+const auxIdleCheckTimeout = 500 * time.Millisecond
+
+// readBufferSize max number of bytes that are read from the serial port at once
+const readBufferSize = 512
+
+// default serial read timeouts, messages are read when AUX reports that the data is ready, so their read is short.
+// Config responses in ModeSleep can come later, their read is retried until the config read timeout expires
+const (
+	defaultReadTimeout       = 100 * time.Millisecond
+	defaultConfigReadTimeout = 2 * time.Second
+)
+
+// writeChunkSize max number of bytes that are passed to the serial port at once, small enough to fit into the
+// TX buffer of common UART drivers
+const writeChunkSize = 64
+
+// fallbackAuxPollInterval AUX polling interval that is used when the kernel doesn't support gpiod edge events
+const fallbackAuxPollInterval = 2 * time.Millisecond
+
+// writeDoneTimeout time in which the module must finish with the written data, air time of the data is added to it
+const writeDoneTimeout = 2 * time.Second
+
 const (
 	actionPowerReset int32 = iota
 	actionRead
 	actionWrite
 	actionModeSwitch
+	actionWritePending // write is requested, but data is not passed to the serial port yet
 )
 
 // chipModeLineState chip mode is defined by two, M0 and M1 inputs. FOr more info read chip doc
@@ -25,103 +53,301 @@ type chipModeLineState struct {
 	m1Value int
 }
 
-// chipModes chip modes defined by the documentations
-var chipModes = map[hal.ChipMode]*chipModeLineState{
+// defaultChipModes chip modes defined by the documentations, every handler gets its own copy of the table
+var defaultChipModes = map[hal.ChipMode]chipModeLineState{
 	hal.ModeNormal:    {m0Value: 0, m1Value: 0},
 	hal.ModeWakeUp:    {m0Value: 1, m1Value: 0},
 	hal.ModePowerSave: {m0Value: 0, m1Value: 1},
 	hal.ModeSleep:     {m0Value: 1, m1Value: 1},
 }
 
+// copyChipModes returns copy of the default chip modes table
+func copyChipModes() map[hal.ChipMode]chipModeLineState {
+	modes := make(map[hal.ChipMode]chipModeLineState, len(defaultChipModes))
+	for mode, lines := range defaultChipModes {
+		modes[mode] = lines
+	}
+	return modes
+}
+
 // serialPortData struct that holds data needed to configure serial port
+// serialBaud, serialParityBit and serialStopBits are the params that are currently applied to the serial port
 type serialPortData struct {
 	serialBaud            int
 	serialParityBit       serial.Parity
+	serialStopBits        serial.StopBits
 	serialBaudStaged      int
 	serialParityBitStaged serial.Parity
+	serialStopBitsStaged  serial.StopBits
+}
+
+// serialPort serial port operations that are used by the handler, implemented by ttyPort
+type serialPort interface {
+	io.ReadWriteCloser
+	Flush() error
+	// Buffered returns number of received bytes that wait in the input buffer, without reading them
+	Buffered() (int, error)
+}
+
+// portOpener function that opens the serial port, tests replace it to use a fake port
+type portOpener func(config *serial.Config) (serialPort, error)
+
+// ttyPort serial port with the input buffer query. serial.Port doesn't expose its file, so the tty is opened
+// once more for the input queue ioctl, the queue belongs to the tty and is the same for both files
+type ttyPort struct {
+	*serial.Port
+	inq *os.File
+}
+
+// Buffered returns number of bytes in the tty input queue
+func (obj *ttyPort) Buffered() (int, error) {
+	return unix.IoctlGetInt(int(obj.inq.Fd()), unix.TIOCINQ)
+}
+
+// Close closes the input queue file and the serial port
+func (obj *ttyPort) Close() error {
+	obj.inq.Close()
+	return obj.Port.Close()
+}
+
+// openSerialPort opens serial port with the given config
+func openSerialPort(config *serial.Config) (serialPort, error) {
+	port, err := serial.OpenPort(config)
+	if err != nil {
+		return nil, err
+	}
+	inq, err := os.OpenFile(config.Name, os.O_RDONLY|unix.O_NOCTTY|unix.O_NONBLOCK, 0)
+	if err != nil {
+		port.Close()
+		return nil, fmt.Errorf("failed to open %s for input queue queries: %w", config.Name, err)
+	}
+	return &ttyPort{Port: port, inq: inq}, nil
 }
 
 // HWHandler data structure
 type HWHandler struct {
-	tty              string                // serial port name
-	serialPortData   *serialPortData       // serial port config data
-	M0Line           *gpiod.Line           // M0 GPIO Pin
-	M1Line           *gpiod.Line           // M1 GPIO Pin
-	AUXLine          *gpiod.Line           // AUX GPIO Pin
-	serialStream     *serial.Port          // serial port needed communicate with the module
-	auxAction        int32                 // action that will be executed on rising edge of AUX pin
-	auxBusyWaitGroup map[string]chan error // holds channels that wait for raising AUX edge
-	writeDone        chan bool             // channel used to notify writer that writing is done on rising AUX edge
-	modeSwitchDone   chan bool             // channel used to notify mode switcher that switching is done on rising AUX edge
-	muAuxDone        sync.Mutex            // map protection mutex
-	muRead           sync.Mutex            // lock reading until previous read is done or timeout
-	muBusy           sync.Mutex            // write, and mode change must be locked until previous write or mode switch operation is done
+	tty              string                             // serial port name
+	serialPortData   *serialPortData                    // serial port config data
+	configBaud       int                                // baud rate used in ModeSleep
+	M0Line           *gpiod.Line                        // M0 GPIO Pin
+	M1Line           *gpiod.Line                        // M1 GPIO Pin
+	AUXLine          *gpiod.Line                        // AUX GPIO Pin
+	serialStream     serialPort                         // serial port needed communicate with the module
+	openPort         portOpener                         // opens serial port on construction and on config update
+	auxLevel         func() (int, error)                // reads AUX line value
+	chipModes        map[hal.ChipMode]chipModeLineState // M0 and M1 line values of every chip mode
+	auxAction        int32                              // action that will be executed on rising edge of AUX pin
+	airBPS           int32                              // air data rate in bits per second, 0 if unknown
+	writeFallSeen    int32                              // 1 if AUX went low after the written data reached the serial port
+	auxBusyWaitGroup map[uint32]chan struct{}           // holds channels that wait for raising AUX edge
+	writeDone        chan bool                          // channel used to notify writer that writing is done on rising AUX edge
+	modeSwitchDone   chan bool                          // channel used to notify mode switcher that switching is done on rising AUX edge
+	auxWaiterSeq     uint32                             // last aux busy group waiter id
+	muAuxDone        sync.Mutex                         // map protection mutex
+	muRead           sync.Mutex                         // lock reading until previous read is done or timeout
+	muBusy           sync.Mutex                         // write, and mode change must be locked until previous write or mode switch operation is done
 	onMsgCb          hal.OnMessageCb
+	onAuxEdgeCb      hal.OnAuxEdgeCb
+	onAuxEdge        func(rising bool, t time.Time) // optional diagnostic hook, called on every AUX edge
+	mode             hal.ChipMode                   // mode that was set by the last successful mode switch
+	modeKnown        bool                           // false if mode was never set, or the last mode switch failed
+	muMode           sync.Mutex                     // mode and modeKnown protection mutex
+	noGPIO           bool                           // GPIO lines are not used, AUX synchronization is replaced with time based waits
+	passiveModeLines bool                           // M0 and M1 lines are inputs, mode is not set by this handler
+	auxPollInterval  time.Duration                  // if set, AUX line is polled instead of using edge events
+	stopPoll         chan struct{}                  // closed on Close, stops serial or AUX polling
+	onRxOverrun      func()                         // optional, called on every suspected receive overrun
+	rxOverruns       uint64                         // number of suspected receive overruns, protected with muStats
+	muStats          sync.Mutex
+	clock            hal.Clock // source of time for timeouts and delays
+
+	readTimeout       time.Duration // serial port read timeout, used for message reads
+	configReadTimeout time.Duration // time during which an empty read is retried in ModeSleep
+
+	closed    bool          // set on Close, AUX edges and polled reads are ignored after it
+	muClosed  sync.RWMutex  // read locked while an AUX edge or a polled read is handled, Close waits for it to finish
+	pollDone  chan struct{} // closed when the serial or AUX poller exits
+	callbacks int32         // number of callbacks that are running, they are called outside of muClosed
+	closeOnce sync.Once
+	closeErr  error // result of the first Close
 }
 
 // NewHWHandler constructs new hardware handler -> handler that is used to communicate and control eByte lora module
-func NewHWHandler(M0Pin int, M1Pin int, AUXPin int, ttyName string, gpioChip string) (*HWHandler, error) {
-	handler := &HWHandler{
+func NewHWHandler(M0Pin int, M1Pin int, AUXPin int, ttyName string, gpioChip string, opts ...HWHandlerOption) (*HWHandler, error) {
+	handler := newHWHandler(ttyName)
+	for _, opt := range opts {
+		opt(handler)
+	}
+	config := &serial.Config{
+		Name:        ttyName,
+		Baud:        handler.serialPortData.serialBaud,
+		Size:        8,
+		ReadTimeout: handler.readTimeout,
+		StopBits:    handler.serialPortData.serialStopBits,
+	}
+	var err error
+	if !handler.noGPIO {
+		err = handler.requestLines(gpioChip, M0Pin, M1Pin, AUXPin)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// both lines are requested as high outputs, passive lines are read when the mode is needed
+	if !handler.passiveModeLines {
+		handler.setTrackedMode(hal.ModeSleep, true)
+	}
+	handler.serialStream, err = handler.openPort(config)
+	if err != nil {
+		handler.closeLines()
+		return nil, fmt.Errorf("failed to open serial port, err: %w", err)
+	}
+	handler.clock.Sleep(200 * time.Millisecond)
+	handler.setAuxAction(actionRead)
+	if handler.noGPIO {
+		handler.stopPoll = make(chan struct{})
+		handler.pollDone = make(chan struct{})
+		go handler.pollSerial()
+	} else if handler.auxPollInterval > 0 {
+		handler.stopPoll = make(chan struct{})
+		handler.pollDone = make(chan struct{})
+		go handler.pollAux()
+	}
+	if !handler.noGPIO {
+		// fail fast if AUX is stuck low, instead of timing out on the first write
+		err = handler.waitAUXIdle(auxIdleCheckTimeout)
+		if err != nil {
+			handler.Close()
+			return nil, fmt.Errorf("AUX never went idle, check wiring and power: %w", err)
+		}
+	}
+	return handler, nil
+}
+
+// newHWHandler constructs handler with default params, without touching the serial port and GPIO lines
+func newHWHandler(ttyName string) *HWHandler {
+	return &HWHandler{
 		tty: ttyName,
 		serialPortData: &serialPortData{
 			serialBaud:            9600,
 			serialParityBit:       serial.ParityNone,
+			serialStopBits:        serial.Stop1,
 			serialBaudStaged:      9600,
 			serialParityBitStaged: serial.ParityNone,
+			serialStopBitsStaged:  serial.Stop1,
 		},
-		auxBusyWaitGroup: make(map[string]chan error),
+		auxBusyWaitGroup: make(map[uint32]chan struct{}),
 		writeDone:        make(chan bool, 1),
 		modeSwitchDone:   make(chan bool, 1),
 		auxAction:        actionPowerReset,
+		configBaud:       9600,
+		clock:            hal.RealClock{},
+
+		readTimeout:       defaultReadTimeout,
+		configReadTimeout: defaultConfigReadTimeout,
+		openPort:          openSerialPort,
+		chipModes:         copyChipModes(),
 	}
-	config := &serial.Config{
-		Name:        ttyName,
-		Baud:        handler.serialPortData.serialBaud,
-		Size:        8,
-		ReadTimeout: 2 * time.Second,
+}
+
+// requestLines requests AUX line as input with edge events, and M0 and M1 lines as high outputs
+func (obj *HWHandler) requestLines(gpioChip string, M0Pin int, M1Pin int, AUXPin int) (err error) {
+	if M0Pin == M1Pin || M0Pin == AUXPin || M1Pin == AUXPin {
+		return fmt.Errorf("M0 [%d], M1 [%d] and AUX [%d] pins must be distinct", M0Pin, M1Pin, AUXPin)
 	}
-	var err error
 	c, err := gpiod.NewChip(gpioChip, gpiod.WithConsumer("ebyte-module"))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create GPIO chip: %w", err)
+		return fmt.Errorf("failed to create GPIO chip: %w", err)
 	}
-
-	handler.AUXLine, err = c.RequestLine(AUXPin, gpiod.WithEventHandler(handler.onAuxPinRiseEvent), gpiod.WithRisingEdge)
+	// lines stay requested after the chip is closed
+	defer c.Close()
+	// release lines that were requested before the failure, so that the construction can be retried
+	defer func() {
+		if err != nil {
+			obj.closeLines()
+		}
+	}()
+	err = checkLinesFree(c, []string{"M0", "M1", "AUX"}, []int{M0Pin, M1Pin, AUXPin})
 	if err != nil {
-		return nil, fmt.Errorf("failed to request AUX GPIO line: %w", err)
+		return err
 	}
 
-	handler.M0Line, err = c.RequestLine(M0Pin, gpiod.AsOutput(1))
+	if obj.auxPollInterval > 0 {
+		obj.AUXLine, err = c.RequestLine(AUXPin, gpiod.AsInput)
+	} else {
+		obj.AUXLine, err = c.RequestLine(AUXPin, gpiod.WithEventHandler(obj.onAuxPinEvent), gpiod.WithBothEdges)
+		if err != nil && !errors.Is(err, gpiod.ErrPermissionDenied) {
+			// older kernels don't support edge events, fall back to polling if the line can be read at all
+			var inputErr error
+			obj.AUXLine, inputErr = c.RequestLine(AUXPin, gpiod.AsInput)
+			if inputErr != nil {
+				return fmt.Errorf("failed to request AUX GPIO line with edge events: %w, as input: %v", err, inputErr)
+			}
+			obj.auxPollInterval = fallbackAuxPollInterval
+			err = nil
+		}
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to request M0 GPIO line: %w", err)
+		return fmt.Errorf("failed to request AUX GPIO line: %w", err)
 	}
+	obj.auxLevel = obj.AUXLine.Value
 
-	handler.M1Line, err = c.RequestLine(M1Pin, gpiod.AsOutput(1))
-	if err != nil {
-		return nil, fmt.Errorf("failed to request M1 GPIO line: %w", err)
+	var modeLineConfig gpiod.LineReqOption = gpiod.AsOutput(1)
+	if obj.passiveModeLines {
+		modeLineConfig = gpiod.AsInput
 	}
-	handler.serialStream, err = serial.OpenPort(config)
+	obj.M0Line, err = c.RequestLine(M0Pin, modeLineConfig)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open serial port, err: %w", err)
+		return fmt.Errorf("failed to request M0 GPIO line: %w", err)
 	}
-	time.Sleep(200 * time.Millisecond)
-	handler.setAuxAction(actionRead)
-	return handler, nil
-}
 
-// Close cleans and closes GPIOs and serial port
-func (obj *HWHandler) Close() (err error) {
-	err = obj.M0Line.Close()
+	obj.M1Line, err = c.RequestLine(M1Pin, modeLineConfig)
 	if err != nil {
-		return fmt.Errorf("failed to close M0 line: %w", err)
+		return fmt.Errorf("failed to request M1 GPIO line: %w", err)
 	}
-	err = obj.M1Line.Close()
-	if err != nil {
-		return fmt.Errorf("failed to close M1 line: %w", err)
+	return nil
+}
+
+// Close cleans and closes GPIOs and serial port, calls after the first one return the result of the first call.
+// AUX edge or polled read that is in progress is waited for. Callbacks are called outside of that, so that a callback
+// can close the handler, a callback that is already running when Close is called can return after Close
+func (obj *HWHandler) Close() error {
+	obj.closeOnce.Do(func() {
+		obj.closeErr = obj.close()
+	})
+	return obj.closeErr
+}
+
+// close stops pollers and closes GPIOs and serial port
+func (obj *HWHandler) close() (err error) {
+	obj.muClosed.Lock()
+	obj.closed = true
+	obj.muClosed.Unlock()
+	// running callback is not waited for, it may be the one that called Close
+	inCallback := atomic.LoadInt32(&obj.callbacks) > 0
+	if obj.stopPoll != nil {
+		close(obj.stopPoll)
+		if !inCallback {
+			<-obj.pollDone
+		}
 	}
-	err = obj.AUXLine.Close()
-	if err != nil {
-		return fmt.Errorf("failed to close AUX line: %w", err)
+	if !obj.noGPIO {
+		err = obj.M0Line.Close()
+		if err != nil {
+			return fmt.Errorf("failed to close M0 line: %w", err)
+		}
+		err = obj.M1Line.Close()
+		if err != nil {
+			return fmt.Errorf("failed to close M1 line: %w", err)
+		}
+		if inCallback {
+			// gpiod waits for the running edge handler when the line is closed
+			go obj.AUXLine.Close()
+		} else {
+			err = obj.AUXLine.Close()
+			if err != nil {
+				return fmt.Errorf("failed to close AUX line: %w", err)
+			}
+		}
 	}
 
 	err = obj.serialStream.Close()
@@ -131,6 +357,18 @@ func (obj *HWHandler) Close() (err error) {
 	return nil
 }
 
+// DrivesModeLines returns true if the handler sets the chip mode on M0 and M1 lines, false for passive mode lines
+// and without GPIO
+func (obj *HWHandler) DrivesModeLines() bool {
+	return !obj.noGPIO && !obj.passiveModeLines
+}
+
+// AuxPolling returns true if AUX line is polled instead of using edge events, either because WithAuxPolling is set,
+// or because the kernel doesn't support edge events
+func (obj *HWHandler) AuxPolling() bool {
+	return obj.auxPollInterval > 0
+}
+
 // RegisterOnMessageCb registers callback method that is called every time when a new message is received
 func (obj *HWHandler) RegisterOnMessageCb(cb hal.OnMessageCb) error {
 	if obj.onMsgCb != nil {
@@ -140,6 +378,15 @@ func (obj *HWHandler) RegisterOnMessageCb(cb hal.OnMessageCb) error {
 	return nil
 }
 
+// RegisterOnAuxEdgeCb registers callback method that is called on every AUX edge that is not caused by write or mode switch
+func (obj *HWHandler) RegisterOnAuxEdgeCb(cb hal.OnAuxEdgeCb) error {
+	if obj.onAuxEdgeCb != nil {
+		return fmt.Errorf("on AUX edge callback already registered")
+	}
+	obj.onAuxEdgeCb = cb
+	return nil
+}
+
 // StageSerialPortConfig set config parameters that will be applied on next updateSerialConfig update
 // there are cases when they can't be applied directly, so we need to stage it first and apply later
 func (obj *HWHandler) StageSerialPortConfig(baudRate int, parityBit serial.Parity) {
@@ -147,14 +394,29 @@ func (obj *HWHandler) StageSerialPortConfig(baudRate int, parityBit serial.Parit
 	obj.serialPortData.serialParityBitStaged = parityBit
 }
 
+// StageSerialStopBits set stop bits that will be applied with the staged serial port config
+// stop bits default to 1, which is what E22 uses, 2 stop bits are needed only on some UART bridges and custom firmware
+func (obj *HWHandler) StageSerialStopBits(stopBits serial.StopBits) {
+	obj.serialPortData.serialStopBitsStaged = stopBits
+}
+
+// CurrentSerialConfig returns baud rate and parity that are currently applied to the serial port.
+// In ModeSleep these are the config mode params, not the ones that are staged for the normal operation
+func (obj *HWHandler) CurrentSerialConfig() (baudRate int, parityBit serial.Parity) {
+	obj.muRead.Lock()
+	defer obj.muRead.Unlock()
+	return obj.serialPortData.serialBaud, obj.serialPortData.serialParityBit
+}
+
 // updateSerialConfig updates RPi serial port config depending on the parameters that are stored on the module
 // at initialization this lib uses baud 9600 to read stored configuration on the module, and if serial config is different than initial one,
 // serial config must be initialized again with the new parameters
-func (obj *HWHandler) updateSerialConfig(serialPortData *serialPortData) (err error) {
+func (obj *HWHandler) updateSerialConfig(baudRate int, parityBit serial.Parity, stopBits serial.StopBits) (err error) {
 
 	// ignore updating if current and next config is the same
-	if serialPortData.serialBaud == serialPortData.serialBaudStaged &&
-		serialPortData.serialParityBit == serialPortData.serialParityBitStaged {
+	if obj.serialPortData.serialBaud == baudRate &&
+		obj.serialPortData.serialParityBit == parityBit &&
+		obj.serialPortData.serialStopBits == stopBits {
 		return nil
 	}
 
@@ -175,22 +437,140 @@ func (obj *HWHandler) updateSerialConfig(serialPortData *serialPortData) (err er
 
 	config := &serial.Config{
 		Name:        obj.tty,
-		Baud:        serialPortData.serialBaudStaged,
+		Baud:        baudRate,
 		Size:        8,
-		ReadTimeout: 2 * time.Second,
-		Parity:      serialPortData.serialParityBitStaged,
+		ReadTimeout: obj.readTimeout,
+		Parity:      parityBit,
+		StopBits:    stopBits,
 	}
-	obj.serialStream, err = serial.OpenPort(config)
+	obj.serialStream, err = obj.openPort(config)
 	if err != nil {
 		return fmt.Errorf("failed to open serial port, err: %w", err)
 	}
-	serialPortData.serialBaud = serialPortData.serialBaudStaged
-	serialPortData.serialParityBit = serialPortData.serialParityBitStaged
+	obj.serialPortData.serialBaud = baudRate
+	obj.serialPortData.serialParityBit = parityBit
+	obj.serialPortData.serialStopBits = stopBits
+	return nil
+}
+
+// SetConfigModeBaud sets baud rate that is used to communicate with the module in ModeSleep.
+// E22 uses 9600 in ModeSleep, change it only if the module doesn't respond on that baud rate.
+// If the module is already in ModeSleep, serial port is reconfigured immediately
+func (obj *HWHandler) SetConfigModeBaud(baudRate int) error {
+	obj.muBusy.Lock()
+	defer obj.muBusy.Unlock()
+	obj.configBaud = baudRate
+	mode, err := obj.GetMode()
+	if err != nil {
+		return err
+	}
+	if mode != hal.ModeSleep {
+		return nil
+	}
+	return obj.useConfigBaud()
+}
+
+// UseConfigBaud sets serial port to the params that the module uses in ModeSleep, without switching the chip mode.
+// In ModeSleep (config mode) E22 always communicates with 9600 8N1, regardless of the baud rate and parity that are
+// stored in REG0. Use it before sending manual register commands to the module that is already in ModeSleep
+func (obj *HWHandler) UseConfigBaud() error {
+	obj.muBusy.Lock()
+	defer obj.muBusy.Unlock()
+	return obj.useConfigBaud()
+}
+
+// RestoreUserBaud sets serial port to the staged params that are stored in the module REG0, without switching the chip mode
+func (obj *HWHandler) RestoreUserBaud() error {
+	obj.muBusy.Lock()
+	defer obj.muBusy.Unlock()
+	return obj.restoreUserBaud()
+}
+
+// useConfigBaud applies config mode serial port params, 9600 8N1 unless changed with SetConfigModeBaud
+func (obj *HWHandler) useConfigBaud() error {
+	err := obj.updateSerialConfig(obj.configBaud, serial.ParityNone, serial.Stop1)
+	if err != nil {
+		return fmt.Errorf("failed to setup serial port params for sleep mode, err: %w", err)
+	}
+	return nil
+}
+
+// restoreUserBaud applies staged serial port params
+func (obj *HWHandler) restoreUserBaud() error {
+	err := obj.updateSerialConfig(obj.serialPortData.serialBaudStaged,
+		obj.serialPortData.serialParityBitStaged, obj.serialPortData.serialStopBitsStaged)
+	if err != nil {
+		return fmt.Errorf("failed to setup serial port params, err: %w", err)
+	}
+	return nil
+}
+
+// closeLines closes GPIO lines that are requested, errors are ignored since it is used for cleanup after a failure
+func (obj *HWHandler) closeLines() {
+	for _, line := range []*gpiod.Line{obj.M0Line, obj.M1Line, obj.AUXLine} {
+		if line != nil {
+			line.Close()
+		}
+	}
+	obj.M0Line, obj.M1Line, obj.AUXLine = nil, nil, nil
+}
+
+// checkLinesFree returns error if any of the given lines is already requested by another consumer
+func checkLinesFree(c *gpiod.Chip, names []string, pins []int) error {
+	for i, pin := range pins {
+		name := names[i]
+		info, err := c.LineInfo(pin)
+		if err != nil {
+			return fmt.Errorf("failed to get %s GPIO line [%d] info: %w", name, pin, err)
+		}
+		if info.Used {
+			return fmt.Errorf("%s GPIO line [%d] is already used by %q", name, pin, info.Consumer)
+		}
+	}
 	return nil
 }
 
-// onAuxPinRiseEvent on aux pin rising edge interrupt handler
-func (obj *HWHandler) onAuxPinRiseEvent(evt gpiod.LineEvent) {
+// onAuxPinEvent on aux pin edge interrupt handler
+// edges that belong to write or mode switch are handled internally, reception edges are forwarded to the registered callback
+func (obj *HWHandler) onAuxPinEvent(evt gpiod.LineEvent) {
+	obj.handleAuxEdge(evt.Type == gpiod.LineEventRisingEdge)
+}
+
+// handleAuxEdge handles AUX edge, independently of the edge source
+func (obj *HWHandler) handleAuxEdge(rising bool) {
+	obj.muClosed.RLock()
+	if obj.closed {
+		obj.muClosed.RUnlock()
+		return
+	}
+	now := obj.clock.Now()
+	action := atomic.LoadInt32(&obj.auxAction)
+	forward := obj.onAuxEdgeCb != nil && (action == actionRead || action == actionWritePending)
+	var data []byte
+	var readErr error
+	if rising {
+		data, readErr = obj.onAuxPinRiseEvent()
+	} else if action == actionWrite {
+		atomic.StoreInt32(&obj.writeFallSeen, 1)
+	}
+	// counted before the unlock, so that Close sees every callback of the edges that are handled before it
+	atomic.AddInt32(&obj.callbacks, 1)
+	obj.muClosed.RUnlock()
+	defer atomic.AddInt32(&obj.callbacks, -1)
+
+	if obj.onAuxEdge != nil {
+		obj.onAuxEdge(rising, now)
+	}
+	if obj.onMsgCb != nil && len(data) > 0 {
+		obj.onMsgCb(data, readErr)
+	}
+	if forward {
+		obj.onAuxEdgeCb(rising, now)
+	}
+}
+
+// onAuxPinRiseEvent on aux pin rising edge handler, returns received data that should be passed to the message callback
+func (obj *HWHandler) onAuxPinRiseEvent() ([]byte, error) {
 	// there is a case when we want to write something to serial or switch chip mode, but the module is busy with reading
 	// on aux rising edge, module is not busy, and operations that wait can be executed
 	defer obj.auxDoneNotifyReceivers()
@@ -200,68 +580,244 @@ func (obj *HWHandler) onAuxPinRiseEvent(evt gpiod.LineEvent) {
 	if currentAction == actionModeSwitch {
 		obj.setAuxAction(actionRead)
 		obj.modeSwitchDone <- true
-		return
+		return nil, nil
 	}
 	if currentAction == actionWrite {
+		if atomic.LoadInt32(&obj.writeFallSeen) == 0 {
+			data, err := obj.readBeforeWriteDone()
+			if len(data) > 0 {
+				// reception that was in progress when the data was written, the module is still busy with the write
+				return data, err
+			}
+		}
 		obj.setAuxAction(actionRead)
 		obj.writeDone <- true
-		return
+		return nil, nil
 	}
-	if currentAction == actionRead {
-		data, err := obj.ReadSerial()
-		if obj.onMsgCb != nil && len(data) > 0 {
-			obj.onMsgCb(data, err)
-		}
-		return
+	// data that is received before the write reaches the chip can't be the write done edge
+	if currentAction == actionRead || currentAction == actionWritePending {
+		return obj.ReadSerial()
 	}
+	return nil, nil
 }
 
-// ReadSerial reads data from the internal buffer register on the module
+// readBeforeWriteDone reads data that is received while the write is in progress.
+// Rising edge of a reception that completes after the write can't be told apart from the write done edge by AUX alone,
+// the received data is left in the serial buffer while the write done edge leaves it empty. The buffer is read only
+// if the port reports buffered data, so the write done edge isn't delayed by the read timeout.
+// Register command responses in ModeSleep are left for the command reader
+func (obj *HWHandler) readBeforeWriteDone() ([]byte, error) {
+	mode, err := obj.GetMode()
+	if err != nil || mode == hal.ModeSleep {
+		return nil, nil
+	}
+	obj.muRead.Lock()
+	buffered, err := obj.serialStream.Buffered()
+	obj.muRead.Unlock()
+	if err != nil || buffered == 0 {
+		return nil, nil
+	}
+	return obj.ReadSerial()
+}
+
+// ReadSerial reads data from the internal buffer register on the module.
+// In ModeSleep an empty read is retried until the config read timeout expires, since the module can take
+// a while to respond to a register command
 func (obj *HWHandler) ReadSerial() ([]byte, error) {
+	mode, _ := obj.GetMode()
 	// read all buffered data, before new read can be performed
 	obj.muRead.Lock()
 	defer obj.muRead.Unlock()
 
-	buf := make([]byte, 512)
+	buf := make([]byte, readBufferSize)
+	deadline := obj.clock.Now().Add(obj.configReadTimeout)
 	n, err := obj.serialStream.Read(buf)
+	for errors.Is(err, io.EOF) && mode == hal.ModeSleep && obj.clock.Now().Before(deadline) {
+		n, err = obj.serialStream.Read(buf)
+	}
 	if err != nil {
 		return []byte{}, fmt.Errorf("failed to receive data: %w", err)
 	}
+	if n == len(buf) {
+		obj.countRxOverrun()
+	}
 	return buf[:n], nil
 }
 
+// countRxOverrun counts a read that filled the whole read buffer, the rest of the received data is likely dropped
+func (obj *HWHandler) countRxOverrun() {
+	obj.muStats.Lock()
+	obj.rxOverruns++
+	obj.muStats.Unlock()
+	if obj.onRxOverrun != nil {
+		obj.onRxOverrun()
+	}
+}
+
+// RxOverruns returns number of reads that filled the whole read buffer. Serial driver doesn't report overruns,
+// so a full read buffer is used as a sign that the received data was more than the reader could take at once
+func (obj *HWHandler) RxOverruns() uint64 {
+	obj.muStats.Lock()
+	defer obj.muStats.Unlock()
+	return obj.rxOverruns
+}
+
+// FlushSerial discards data that is received but not read, and data that is written but not transmitted
+func (obj *HWHandler) FlushSerial() error {
+	obj.muRead.Lock()
+	defer obj.muRead.Unlock()
+
+	err := obj.serialStream.Flush()
+	if err != nil {
+		return fmt.Errorf("failed to flush serial stream: %w", err)
+	}
+	return nil
+}
+
 // WriteSerial writes given byte array to serial port
 func (obj *HWHandler) WriteSerial(msg []byte) error {
+	return obj.WriteSerialContext(context.Background(), msg)
+}
+
+// WriteSerialContext writes given byte array to serial port like WriteSerial, waiting for the module is aborted
+// with ctx error when ctx is done. Data that is already passed to the serial port is not recalled
+func (obj *HWHandler) WriteSerialContext(ctx context.Context, msg []byte) error {
 	// lock it, another write or mode switch can't happen before this writing finishes
 	obj.muBusy.Lock()
 	defer obj.muBusy.Unlock()
 
 	// check if module is busy, wait for previous action to finish
-	err := obj.registerAndWaitAUXDone()
+	err := obj.waitAUXIdleContext(ctx, 2*time.Second)
 	if err != nil {
 		return fmt.Errorf("failed to check AUX pin input state: %w", err)
 	}
-	obj.setAuxAction(actionWrite)
+	obj.setAuxAction(actionWritePending)
 
-	_, err = obj.serialStream.Write(msg)
+	err = obj.writeChunked(msg)
 	if err != nil {
+		obj.setAuxAction(actionRead)
 		return fmt.Errorf("failed to send data, err: %w", err)
 	}
+	// drop done signal of a previous write that timed out
+	drainDone(obj.writeDone)
+	// from now on, the next rising edge means that the chip is done with the written data,
+	// unless it is preceded only by the end of a reception
+	atomic.StoreInt32(&obj.writeFallSeen, 0)
+	obj.setAuxAction(actionWrite)
+	if obj.noGPIO {
+		obj.setAuxAction(actionRead)
+		obj.clock.Sleep(noGPIOBusyWait)
+		return nil
+	}
 
 	select {
-	case <-time.After(2 * time.Second):
-		return fmt.Errorf("failed to send data, timeout ocurred")
+	case <-ctx.Done():
+		obj.abortWait(obj.writeDone)
+		return ctx.Err()
+	case <-obj.clock.After(obj.writeDoneTimeout(len(msg))):
+		obj.abortWait(obj.writeDone)
+		return fmt.Errorf("failed to send data: %w", hal.ErrChipBusyTimeout)
 	case <-obj.writeDone:
 	}
 
 	// module needs 2ms to switch from busy mode to non busy mode after rising aux edge
-	time.Sleep(2 * time.Millisecond)
+	obj.clock.Sleep(2 * time.Millisecond)
 	return nil
 }
 
+// WriteSerialBurst writes frames one after another, with a gap that makes the module transmit them as separate packets.
+// AUX is checked only before the first frame, and the write is done when the module is idle after the last frame
+func (obj *HWHandler) WriteSerialBurst(frames [][]byte) error {
+	obj.muBusy.Lock()
+	defer obj.muBusy.Unlock()
+
+	err := obj.registerAndWaitAUXDone()
+	if err != nil {
+		return fmt.Errorf("failed to check AUX pin input state: %w", err)
+	}
+	obj.setAuxAction(actionWritePending)
+
+	// module splits packets on 3 bytes of silence on the serial line
+	gap := 3*10*time.Second/time.Duration(obj.serialPortData.serialBaud) + time.Millisecond
+	for i, frame := range frames {
+		if i > 0 {
+			obj.clock.Sleep(gap)
+		}
+		err = obj.writeChunked(frame)
+		if err != nil {
+			obj.setAuxAction(actionRead)
+			return fmt.Errorf("failed to send frame %d, err: %w", i, err)
+		}
+	}
+	if obj.noGPIO {
+		obj.setAuxAction(actionRead)
+		obj.clock.Sleep(noGPIOBusyWait)
+		return nil
+	}
+	drainDone(obj.writeDone)
+	atomic.StoreInt32(&obj.writeFallSeen, 0)
+	obj.setAuxAction(actionWrite)
+
+	timeout := time.Duration(0)
+	for _, frame := range frames {
+		timeout += obj.writeDoneTimeout(len(frame))
+	}
+	select {
+	case <-obj.clock.After(timeout):
+		obj.abortWait(obj.writeDone)
+		return fmt.Errorf("failed to send burst: %w", hal.ErrChipBusyTimeout)
+	case <-obj.writeDone:
+	}
+	obj.clock.Sleep(2 * time.Millisecond)
+	return nil
+}
+
+// writeChunked writes data to the serial port in writeChunkSize chunks, short writes are continued
+func (obj *HWHandler) writeChunked(data []byte) error {
+	for len(data) > 0 {
+		chunk := data
+		if len(chunk) > writeChunkSize {
+			chunk = chunk[:writeChunkSize]
+		}
+		n, err := obj.serialStream.Write(chunk)
+		if err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+// SetAirDataRate sets air data rate that is configured on the module. Write done timeout is extended by
+// the air time of the written data, so that large writes at low air data rates don't time out
+func (obj *HWHandler) SetAirDataRate(bitsPerSecond int) {
+	atomic.StoreInt32(&obj.airBPS, int32(bitsPerSecond))
+}
+
+// writeDoneTimeout returns time in which the module must be done with n written bytes
+func (obj *HWHandler) writeDoneTimeout(n int) time.Duration {
+	bps := atomic.LoadInt32(&obj.airBPS)
+	if bps <= 0 {
+		return writeDoneTimeout
+	}
+	return writeDoneTimeout + time.Duration(n*8)*time.Second/time.Duration(bps)
+}
+
 // SetMode sets ebyte module to given mode
 func (obj *HWHandler) SetMode(mode hal.ChipMode) error {
+	return obj.SetModeContext(context.Background(), mode)
+}
+
+// SetModeContext sets ebyte module to given mode like SetMode, waiting for the module is aborted with ctx error
+// when ctx is done. Mode is unknown if the wait is aborted after M0 and M1 lines are set, GetMode reads the lines then
+func (obj *HWHandler) SetModeContext(ctx context.Context, mode hal.ChipMode) error {
 	// lock it, another write or mode switch can't happen before this mode switching finishes
+	// current mode is checked under the lock, so that concurrent mode switches see the result of each other
+	if obj.passiveModeLines {
+		return fmt.Errorf("failed to set chip mode [%d], mode lines are passive", mode)
+	}
+	obj.muBusy.Lock()
+	defer obj.muBusy.Unlock()
 	currentMode, err := obj.GetMode()
 	if err != nil {
 		return err
@@ -269,38 +825,40 @@ func (obj *HWHandler) SetMode(mode hal.ChipMode) error {
 	if currentMode == mode {
 		return nil
 	}
-	obj.muBusy.Lock()
-	defer obj.muBusy.Unlock()
-	chipMode, ok := chipModes[mode]
+	chipMode, ok := obj.chipModes[mode]
 	if !ok {
 		return fmt.Errorf("failed to set unsupported chip mode: %d", mode)
 	}
 
 	if mode == hal.ModeSleep {
-		err := obj.updateSerialConfig(&serialPortData{
-			serialBaud:            obj.serialPortData.serialBaud,
-			serialParityBit:       obj.serialPortData.serialParityBit,
-			serialBaudStaged:      9600,
-			serialParityBitStaged: serial.ParityNone,
-		})
-		if err != nil {
-			return fmt.Errorf("failed to setup serial port params for sleep mode, err: %w", err)
-		}
+		err = obj.useConfigBaud()
 	} else {
-		err := obj.updateSerialConfig(obj.serialPortData)
-		if err != nil {
-			return fmt.Errorf("failed to setup serial port params for sleep mode, err: %w", err)
-		}
+		err = obj.restoreUserBaud()
+	}
+	if err != nil {
+		return err
 	}
 	// check if module is busy, wait for previous action to finish
-	err = obj.registerAndWaitAUXDone()
+	err = obj.waitAUXIdleContext(ctx, 2*time.Second)
 	if err != nil {
 		return fmt.Errorf("failed to check AUX pin input state: %w", err)
 	}
 
+	// drop done signal of a previous mode switch that timed out, so that it isn't taken as the signal of this one
+	drainDone(obj.modeSwitchDone)
 	// set aux action to mode switch
 	obj.setAuxAction(actionModeSwitch)
 
+	if obj.noGPIO {
+		obj.setAuxAction(actionRead)
+		obj.setTrackedMode(mode, true)
+		obj.clock.Sleep(noGPIOBusyWait)
+		return nil
+	}
+
+	// mode is unknown until the switch is completed
+	obj.setTrackedMode(mode, false)
+
 	err = obj.M0Line.SetValue(chipMode.m0Value)
 	if err != nil {
 		return fmt.Errorf("failed to set mode [%d] on M0 line, err: %w", mode, err)
@@ -308,17 +866,22 @@ func (obj *HWHandler) SetMode(mode hal.ChipMode) error {
 
 	err = obj.M1Line.SetValue(chipMode.m1Value)
 	if err != nil {
-		return fmt.Errorf("failed to set mode [%d] on M1 line, err %w", mode, err)
+		return fmt.Errorf("failed to set mode [%d] on M1 line, err: %w", mode, err)
 	}
 
 	select {
-	case <-time.After(2 * time.Second):
-		return fmt.Errorf("failed to switch chip mode, timeout ocurred")
+	case <-ctx.Done():
+		obj.abortWait(obj.modeSwitchDone)
+		return ctx.Err()
+	case <-obj.clock.After(2 * time.Second):
+		obj.abortWait(obj.modeSwitchDone)
+		return fmt.Errorf("failed to switch chip mode: %w", hal.ErrChipBusyTimeout)
 	case <-obj.modeSwitchDone:
 	}
+	obj.setTrackedMode(mode, true)
 	// documentation says that the mode switching is not completed on raising edge. It needs 2 ms.
 	// waiting 200 just to be sure
-	time.Sleep(200 * time.Millisecond)
+	obj.clock.Sleep(200 * time.Millisecond)
 	return nil
 }
 
@@ -327,52 +890,97 @@ func (obj *HWHandler) auxDoneNotifyReceivers() {
 	obj.muAuxDone.Lock()
 	defer obj.muAuxDone.Unlock()
 	for id, ch := range obj.auxBusyWaitGroup {
-		ch <- nil
 		close(ch)
 		delete(obj.auxBusyWaitGroup, id)
 	}
-
 }
 
 // registerAndWaitAUXDone adds new aux done listener to aux busy group
 func (obj *HWHandler) registerAndWaitAUXDone() error {
-	val, err := obj.AUXLine.Value()
+	return obj.waitAUXIdle(2 * time.Second)
+}
+
+// WaitAUXIdle waits until AUX line is high, the module keeps it low while it is busy, including the over the air
+// transmission. Returns error that wraps hal.ErrChipBusyTimeout if the module is still busy after timeout
+func (obj *HWHandler) WaitAUXIdle(timeout time.Duration) error {
+	return obj.waitAUXIdle(timeout)
+}
+
+// waitAUXIdle waits for the AUX rising edge if the AUX line is low
+func (obj *HWHandler) waitAUXIdle(timeout time.Duration) error {
+	return obj.waitAUXIdleContext(context.Background(), timeout)
+}
+
+// waitAUXIdleContext waits for the AUX rising edge if the AUX line is low, the wait is aborted when ctx is done
+func (obj *HWHandler) waitAUXIdleContext(ctx context.Context, timeout time.Duration) error {
+	if obj.noGPIO {
+		return nil
+	}
+	val, err := obj.auxLevel()
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to get AUX line value: %w", err)
 	}
 	if val == 1 {
 		return nil
 	}
 
-	ch := make(chan error)
-	id, err := random.String(16)
-	if err != nil {
-		return fmt.Errorf("failed to generate random id: %w", err)
-	}
+	// waiters are notified by closing the channel, so the notifier never blocks on a waiter that timed out
+	ch := make(chan struct{})
+	id := atomic.AddUint32(&obj.auxWaiterSeq, 1)
 	obj.muAuxDone.Lock()
 	obj.auxBusyWaitGroup[id] = ch
 	obj.muAuxDone.Unlock()
 	select {
-	case <-time.After(2 * time.Second):
-		return fmt.Errorf("aux free checking timeouted")
+	case <-ctx.Done():
+		obj.muAuxDone.Lock()
+		delete(obj.auxBusyWaitGroup, id)
+		obj.muAuxDone.Unlock()
+		return ctx.Err()
+	case <-obj.clock.After(timeout):
+		obj.muAuxDone.Lock()
+		delete(obj.auxBusyWaitGroup, id)
+		obj.muAuxDone.Unlock()
+		return fmt.Errorf("aux free checking: %w", hal.ErrChipBusyTimeout)
 	case <-ch:
 		return nil
 	}
-
 }
 
-// GetMode returns current module mode, depending on M0,M! GPIO input state
+// GetMode returns current module mode. The mode set by the last successful SetMode is returned, because on some
+// gpiod setups output lines can't be read back. M0 and M1 lines are read only when the mode is not known
 func (obj *HWHandler) GetMode() (hal.ChipMode, error) {
+	obj.muMode.Lock()
+	mode, known := obj.mode, obj.modeKnown
+	obj.muMode.Unlock()
+	if known {
+		return mode, nil
+	}
+	return obj.readModeLines()
+}
+
+// setTrackedMode stores the mode that is set on M0 and M1 lines
+func (obj *HWHandler) setTrackedMode(mode hal.ChipMode, known bool) {
+	obj.muMode.Lock()
+	defer obj.muMode.Unlock()
+	obj.mode = mode
+	obj.modeKnown = known
+}
+
+// readModeLines returns current module mode, depending on M0,M1 GPIO state
+func (obj *HWHandler) readModeLines() (hal.ChipMode, error) {
+	if obj.noGPIO {
+		return 0, fmt.Errorf("mode lines are not available without GPIO")
+	}
 	m0Val, err := obj.M0Line.Value()
 	if err != nil {
 		return 0, fmt.Errorf("failed to get M0 line value, err: %w", err)
 	}
 	m1Val, err := obj.M1Line.Value()
 	if err != nil {
-		return 0, fmt.Errorf("failed to get M0 line value, err: %w", err)
+		return 0, fmt.Errorf("failed to get M1 line value, err: %w", err)
 	}
 
-	for mode, values := range chipModes {
+	for mode, values := range obj.chipModes {
 		if values.m0Value == m0Val && values.m1Value == m1Val {
 			return mode, nil
 		}
@@ -380,8 +988,22 @@ func (obj *HWHandler) GetMode() (hal.ChipMode, error) {
 	return 0, fmt.Errorf("chip is in some weird undefined mode. Check connection")
 }
 
+// drainDone removes pending done signal from the channel, if there is one
+func drainDone(ch chan bool) {
+	select {
+	case <-ch:
+	default:
+	}
+}
+
+// abortWait stops waiting for the done signal, the next rising edge is handled as a read, and the signal that was
+// sent between the abort and the action reset is dropped
+func (obj *HWHandler) abortWait(done chan bool) {
+	obj.setAuxAction(actionRead)
+	drainDone(done)
+}
+
 // setAuxAction sets given action read/write/modeSwitch as a next action that will be performed on aux event
 func (obj *HWHandler) setAuxAction(action int32) {
 	atomic.StoreInt32(&obj.auxAction, action)
-	obj.auxAction = action
 }
@@ -0,0 +1,246 @@
+package common
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mbalug7/go-ebyte-lora/pkg/hal"
+	"github.com/tarm/serial"
+)
+
+// fakeSerial serial port that returns queued data on read, and records written data
+type fakeSerial struct {
+	mu      sync.Mutex
+	rx      []byte
+	tx      []byte
+	opened  int
+	closed  int
+	reads   int
+	written chan struct{}
+}
+
+func newFakeSerial() *fakeSerial {
+	return &fakeSerial{written: make(chan struct{}, 16)}
+}
+
+func (obj *fakeSerial) Read(p []byte) (int, error) {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	obj.reads++
+	if len(obj.rx) == 0 {
+		// tarm/serial returns EOF when the read times out
+		time.Sleep(time.Millisecond)
+		return 0, io.EOF
+	}
+	n := copy(p, obj.rx)
+	obj.rx = obj.rx[n:]
+	return n, nil
+}
+
+func (obj *fakeSerial) Write(p []byte) (int, error) {
+	obj.mu.Lock()
+	obj.tx = append(obj.tx, p...)
+	obj.mu.Unlock()
+	select {
+	case obj.written <- struct{}{}:
+	default:
+	}
+	return len(p), nil
+}
+
+func (obj *fakeSerial) Flush() error {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	obj.rx = nil
+	return nil
+}
+
+func (obj *fakeSerial) Buffered() (int, error) {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	return len(obj.rx), nil
+}
+
+// readCount returns number of reads
+func (obj *fakeSerial) readCount() int {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	return obj.reads
+}
+
+func (obj *fakeSerial) Close() error {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	obj.closed++
+	return nil
+}
+
+// queue makes data available to the next read
+func (obj *fakeSerial) queue(data []byte) {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	obj.rx = append(obj.rx, data...)
+}
+
+// sent returns data that is written to the port
+func (obj *fakeSerial) sent() []byte {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	return append([]byte(nil), obj.tx...)
+}
+
+// fakeAUX AUX line level that is set by the test
+type fakeAUX struct {
+	level int32
+}
+
+func (obj *fakeAUX) value() (int, error) {
+	return int(atomic.LoadInt32(&obj.level)), nil
+}
+
+func (obj *fakeAUX) set(level int) {
+	atomic.StoreInt32(&obj.level, int32(level))
+}
+
+// newTestHandler constructs handler that uses fake serial port and AUX line, M0 and M1 lines are not used
+func newTestHandler(t *testing.T, noGPIO bool) (*HWHandler, *fakeSerial, *fakeAUX) {
+	t.Helper()
+	port := newFakeSerial()
+	aux := &fakeAUX{level: 1}
+	handler := newHWHandler("fake")
+	handler.noGPIO = noGPIO
+	handler.openPort = func(config *serial.Config) (serialPort, error) {
+		port.mu.Lock()
+		port.opened++
+		port.mu.Unlock()
+		return port, nil
+	}
+	handler.serialStream = port
+	handler.auxLevel = aux.value
+	handler.setTrackedMode(hal.ModeNormal, true)
+	handler.setAuxAction(actionRead)
+	return handler, port, aux
+}
+
+// waitAuxAction waits until the handler expects the given action on the next AUX edge
+func waitAuxAction(t *testing.T, handler *HWHandler, action int32) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&handler.auxAction) != action {
+		if time.Now().After(deadline) {
+			t.Fatalf("aux action is %d, expected %d", atomic.LoadInt32(&handler.auxAction), action)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestConcurrentSetMode(t *testing.T) {
+	handler, _, _ := newTestHandler(t, true)
+	handler.StageSerialPortConfig(115200, serial.ParityNone)
+
+	modes := []hal.ChipMode{hal.ModeSleep, hal.ModeNormal, hal.ModeWakeUp, hal.ModeSleep, hal.ModePowerSave, hal.ModeNormal}
+	var wg sync.WaitGroup
+	for _, mode := range modes {
+		wg.Add(1)
+		go func(mode hal.ChipMode) {
+			defer wg.Done()
+			err := handler.SetMode(mode)
+			if err != nil {
+				t.Errorf("failed to set mode %d: %v", mode, err)
+			}
+		}(mode)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			handler.GetMode()
+			handler.CurrentSerialConfig()
+		}()
+	}
+	wg.Wait()
+
+	mode, err := handler.GetMode()
+	if err != nil {
+		t.Fatalf("failed to get mode: %v", err)
+	}
+	baud, _ := handler.CurrentSerialConfig()
+	expected := 115200
+	if mode == hal.ModeSleep {
+		expected = 9600
+	}
+	if baud != expected {
+		t.Fatalf("serial baud is %d in mode %d, expected %d", baud, mode, expected)
+	}
+}
+
+func TestChipModesArePerHandler(t *testing.T) {
+	first, _, _ := newTestHandler(t, true)
+	second, _, _ := newTestHandler(t, true)
+
+	first.chipModes[hal.ModeNormal] = chipModeLineState{m0Value: 1, m1Value: 1}
+	delete(first.chipModes, hal.ModeWakeUp)
+
+	expected := chipModeLineState{m0Value: 0, m1Value: 0}
+	if second.chipModes[hal.ModeNormal] != expected {
+		t.Fatalf("second handler normal mode lines are %+v, expected %+v", second.chipModes[hal.ModeNormal], expected)
+	}
+	if _, ok := second.chipModes[hal.ModeWakeUp]; !ok {
+		t.Fatal("wake up mode is removed from the second handler")
+	}
+	if defaultChipModes[hal.ModeNormal] != expected {
+		t.Fatalf("default normal mode lines are %+v, expected %+v", defaultChipModes[hal.ModeNormal], expected)
+	}
+	if len(defaultChipModes) != 4 {
+		t.Fatalf("default table has %d modes, expected 4", len(defaultChipModes))
+	}
+}
+
+func TestCloseFromMessageCallback(t *testing.T) {
+	handler, port, _ := newTestHandler(t, true)
+	closed := make(chan error, 1)
+	handler.RegisterOnMessageCb(func(data []byte, err error) {
+		closed <- handler.Close()
+	})
+	handler.stopPoll = make(chan struct{})
+	handler.pollDone = make(chan struct{})
+	go handler.pollSerial()
+
+	port.queue([]byte("bye"))
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatalf("close failed: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("close called from the message callback didn't return")
+	}
+	select {
+	case <-handler.pollDone:
+	case <-time.After(time.Second):
+		t.Fatal("serial poller didn't stop after close")
+	}
+	if err := handler.Close(); err != nil {
+		t.Fatalf("second close failed: %v", err)
+	}
+	port.mu.Lock()
+	defer port.mu.Unlock()
+	if port.closed != 1 {
+		t.Fatalf("serial port is closed %d times, expected once", port.closed)
+	}
+}
+
+func TestPassiveModeLines(t *testing.T) {
+	handler, _, _ := newTestHandler(t, false)
+	if !handler.DrivesModeLines() {
+		t.Fatal("handler with GPIO doesn't report that it drives mode lines")
+	}
+	WithPassiveModeLines()(handler)
+	if handler.DrivesModeLines() {
+		t.Fatal("handler with passive mode lines reports that it drives them")
+	}
+	if err := handler.SetMode(hal.ModeSleep); err == nil {
+		t.Fatal("mode is set on passive mode lines")
+	}
+}
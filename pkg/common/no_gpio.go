@@ -0,0 +1,44 @@
+package common
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/mbalug7/go-ebyte-lora/pkg/hal"
+)
+
+// noGPIOBusyWait time that replaces waiting for the AUX rising edge after write and mode switch when GPIO is not used
+const noGPIOBusyWait = 100 * time.Millisecond
+
+// noGPIOPollInterval interval of the serial polling while the chip is in ModeSleep, when GPIO is not used
+const noGPIOPollInterval = 50 * time.Millisecond
+
+// pollSerial replaces AUX triggered reads when GPIO is not used. Data is read continuously and passed to the message
+// callback, except in ModeSleep where the received data is a register command response that is read by the module
+func (obj *HWHandler) pollSerial() {
+	defer close(obj.pollDone)
+	for {
+		select {
+		case <-obj.stopPoll:
+			return
+		default:
+		}
+		mode, err := obj.GetMode()
+		if err != nil || mode == hal.ModeSleep {
+			obj.clock.Sleep(noGPIOPollInterval)
+			continue
+		}
+		obj.muClosed.RLock()
+		if obj.closed {
+			obj.muClosed.RUnlock()
+			return
+		}
+		data, err := obj.ReadSerial()
+		atomic.AddInt32(&obj.callbacks, 1)
+		obj.muClosed.RUnlock()
+		if obj.onMsgCb != nil && len(data) > 0 {
+			obj.onMsgCb(data, err)
+		}
+		atomic.AddInt32(&obj.callbacks, -1)
+	}
+}
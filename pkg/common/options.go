@@ -0,0 +1,69 @@
+package common
+
+import (
+	"time"
+
+	"github.com/mbalug7/go-ebyte-lora/pkg/hal"
+)
+
+// HWHandlerOption defines optional HWHandler behaviour that can be passed to NewHWHandler
+type HWHandlerOption func(*HWHandler)
+
+// WithOnAuxEdge registers diagnostic hook that is called on every AUX edge, including write and mode switch edges.
+// Use it to confirm that AUX is toggling on a new board, and to measure how long the module stays busy
+func WithOnAuxEdge(hook func(rising bool, t time.Time)) HWHandlerOption {
+	return func(obj *HWHandler) {
+		obj.onAuxEdge = hook
+	}
+}
+
+// WithoutGPIO runs the handler purely over serial, M0, M1 and AUX lines are not requested and gpioChip and pin numbers
+// are ignored. Mode switch is only tracked, waiting for AUX is replaced with fixed delays, and received data is polled.
+// Use it with a virtual serial port (e.g. socat pty) for development and integration tests
+func WithoutGPIO() HWHandlerOption {
+	return func(obj *HWHandler) {
+		obj.noGPIO = true
+	}
+}
+
+// WithPassiveModeLines requests M0 and M1 lines as inputs, so the mode that is set by someone else is left untouched.
+// SetMode returns error, and GetMode reads the lines. Use it for the handler of e22.NewObserverModule
+func WithPassiveModeLines() HWHandlerOption {
+	return func(obj *HWHandler) {
+		obj.passiveModeLines = true
+	}
+}
+
+// WithAuxPolling reads AUX line value every interval instead of using edge events. Use it on boards where gpiod edge
+// events are unreliable. Edges shorter than interval are missed, keep it in the low milliseconds.
+// Polling is used automatically if the kernel doesn't support edge events, see HWHandler.AuxPolling
+func WithAuxPolling(interval time.Duration) HWHandlerOption {
+	return func(obj *HWHandler) {
+		obj.auxPollInterval = interval
+	}
+}
+
+// WithOnRxOverrun registers hook that is called when a read fills the whole read buffer, which means that the
+// received data is likely lost because the reader is too slow. See RxOverruns
+func WithOnRxOverrun(hook func()) HWHandlerOption {
+	return func(obj *HWHandler) {
+		obj.onRxOverrun = hook
+	}
+}
+
+// WithClock replaces the clock that is used for timeouts and delays, use hal.FakeClock in tests
+func WithClock(clock hal.Clock) HWHandlerOption {
+	return func(obj *HWHandler) {
+		obj.clock = clock
+	}
+}
+
+// WithReadTimeouts sets serial read timeouts. Message read waits at most message for the data, it should be short
+// since messages are read when AUX reports that the data is ready. Register command responses in ModeSleep are
+// waited for at most config. Defaults are 100 ms and 2 s
+func WithReadTimeouts(message time.Duration, config time.Duration) HWHandlerOption {
+	return func(obj *HWHandler) {
+		obj.readTimeout = message
+		obj.configReadTimeout = config
+	}
+}
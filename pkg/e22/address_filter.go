@@ -0,0 +1,42 @@
+package e22
+
+import "fmt"
+
+// BroadcastAddress destination address that is accepted by every node that uses WithRxAddressFilter
+const BroadcastAddress uint16 = 0xFFFF
+
+// addressHeaderSize size of the software destination address header
+const addressHeaderSize = 2
+
+// filterAddress returns payload without the address header, and false if the frame is not addressed to this node
+func (obj *Module) filterAddress(frame []byte) ([]byte, bool) {
+	if len(frame) < addressHeaderSize {
+		return nil, false
+	}
+	dest := uint16(frame[0])<<8 | uint16(frame[1])
+	if dest != obj.rxAddress && dest != BroadcastAddress {
+		return nil, false
+	}
+	return frame[addressHeaderSize:], true
+}
+
+// SendAddressed sends payload prefixed with the 2 byte big endian destination address.
+// All nodes on the channel receive it, only the node that uses WithRxAddressFilter with dest delivers it
+func (obj *Module) SendAddressed(dest uint16, payload []byte) error {
+	msg := append([]byte{byte(dest >> 8), byte(dest)}, payload...)
+	obj.muSend.Lock()
+	defer obj.muSend.Unlock()
+	err := obj.checkTransparent()
+	if err != nil {
+		return err
+	}
+	err = obj.checkPayloadSize(msg)
+	if err != nil {
+		return err
+	}
+	err = obj.writeMessage(msg)
+	if err != nil {
+		return fmt.Errorf("failed to send addressed message: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package e22
+
+import (
+	"errors"
+
+	"github.com/mbalug7/go-ebyte-lora/pkg/hal"
+)
+
+// startAmbientSampler starts ambient RSSI sampling if it is enabled with WithAmbientRSSISampling
+func (obj *Module) startAmbientSampler() {
+	if obj.ambientInterval <= 0 || len(obj.ambientSamples) == 0 || obj.observer {
+		return
+	}
+	obj.stopAmbient = make(chan struct{})
+	obj.ambientDone = make(chan struct{})
+	go func() {
+		defer close(obj.ambientDone)
+		for {
+			select {
+			case <-obj.stopAmbient:
+				return
+			case <-obj.clock.After(obj.ambientInterval):
+			}
+			obj.sampleAmbientRSSI()
+		}
+	}()
+}
+
+// stopAmbientSampler stops ambient RSSI sampling and waits for the sampler to exit
+func (obj *Module) stopAmbientSampler() {
+	if obj.stopAmbient == nil {
+		return
+	}
+	close(obj.stopAmbient)
+	<-obj.ambientDone
+	obj.stopAmbient = nil
+}
+
+// sampleAmbientRSSI reads one ambient RSSI sample, the sample is skipped if the chip is not idle
+// or not in a mode that answers the RSSI read command, and never taken on firmware that doesn't support the command
+func (obj *Module) sampleAmbientRSSI() {
+	if obj.currentRegisters()[REG1].(*Reg1).ambientNoiseRSSI != RSSI_AMBIENT_NOISE_ENABLE {
+		return
+	}
+	if obj.requireFirmware(FeatureChannelRSSI) != nil {
+		return
+	}
+	obj.muSend.Lock()
+	defer obj.muSend.Unlock()
+	mode, err := obj.hw.GetMode()
+	if err != nil || (mode != hal.ModeNormal && mode != hal.ModeWakeUp) {
+		return
+	}
+	// don't wait for the chip that is transmitting or receiving, try again on the next tick. Without the AUX state
+	// the sample is taken anyway, the send lock still keeps it away from sends
+	err = obj.waitAUXIdle(0)
+	if err != nil && !errors.Is(err, hal.ErrNotSupported) {
+		return
+	}
+	ambient, _, err := obj.queryChannelRSSI()
+	if err != nil {
+		return
+	}
+	obj.muStats.Lock()
+	defer obj.muStats.Unlock()
+	obj.ambientSamples[obj.ambientNext] = ambient
+	obj.ambientNext = (obj.ambientNext + 1) % len(obj.ambientSamples)
+	if obj.ambientNext == 0 {
+		obj.ambientFull = true
+	}
+}
+
+// RecentAmbientRSSI returns raw ambient RSSI samples, the oldest first. Use AmbientRSSIdBm to convert them.
+// Returns nil if the module is not created with WithAmbientRSSISampling
+func (obj *Module) RecentAmbientRSSI() []uint8 {
+	obj.muStats.Lock()
+	defer obj.muStats.Unlock()
+	if len(obj.ambientSamples) == 0 {
+		return nil
+	}
+	if !obj.ambientFull {
+		return append([]uint8{}, obj.ambientSamples[:obj.ambientNext]...)
+	}
+	samples := make([]uint8, 0, len(obj.ambientSamples))
+	samples = append(samples, obj.ambientSamples[obj.ambientNext:]...)
+	return append(samples, obj.ambientSamples[:obj.ambientNext]...)
+}
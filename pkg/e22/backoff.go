@@ -0,0 +1,85 @@
+package e22
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	backoffInitialDelay = 500 * time.Millisecond
+	backoffMaxDelay     = 30 * time.Second
+)
+
+// fixedTarget identifies fixed transmission destination
+type fixedTarget struct {
+	addressHigh byte
+	addressLow  byte
+	channel     byte
+}
+
+// backoffState holds send failure bookkeeping for one fixed target
+type backoffState struct {
+	failures    int
+	nextAttempt time.Time
+}
+
+// delay returns backoff interval for the current number of consecutive failures
+func (obj *backoffState) delay() time.Duration {
+	d := backoffInitialDelay
+	for i := 1; i < obj.failures && d < backoffMaxDelay; i++ {
+		d *= 2
+	}
+	if d > backoffMaxDelay {
+		d = backoffMaxDelay
+	}
+	return d
+}
+
+// isTransmitFailure returns true for busy timeouts and failed writes to the chip. Caller errors (e.g. invalid payload,
+// wrong chip mode or transmission method) and a closed port fail every attempt, backing off doesn't help with them
+func isTransmitFailure(err error) bool {
+	if errors.Is(err, os.ErrClosed) {
+		return false
+	}
+	var transmitErr *transmitError
+	return errors.Is(err, ErrChipBusyTimeout) || errors.As(err, &transmitErr)
+}
+
+// SendFixedReliable sends fixed message like SendFixedMessage, but keeps per-target backoff state.
+// Every consecutive transmit failure (busy timeout or failed write to the chip) to the same address and channel
+// doubles the interval in which new sends to that target are rejected with ErrTargetBackoff, and a successful send
+// resets it. Other errors are returned right away and don't change the backoff state.
+// There is no acknowledgement from the receiver, so success means that the module accepted the frame for transmission
+func (obj *Module) SendFixedReliable(addressHigh byte, addressLow byte, channel byte, message string) error {
+	target := fixedTarget{addressHigh: addressHigh, addressLow: addressLow, channel: channel}
+
+	obj.muBackoff.Lock()
+	state, ok := obj.backoff[target]
+	if ok && obj.clock.Now().Before(state.nextAttempt) {
+		obj.muBackoff.Unlock()
+		return fmt.Errorf("%w: address 0x%02X%02X, channel %d, %d failures, retry in %s",
+			ErrTargetBackoff, addressHigh, addressLow, channel, state.failures, state.nextAttempt.Sub(obj.clock.Now()).Round(time.Millisecond))
+	}
+	obj.muBackoff.Unlock()
+
+	err := obj.SendFixedMessage(addressHigh, addressLow, channel, message)
+
+	obj.muBackoff.Lock()
+	defer obj.muBackoff.Unlock()
+	if err == nil {
+		delete(obj.backoff, target)
+		return nil
+	}
+	if !isTransmitFailure(err) {
+		return err
+	}
+	if !ok {
+		state = &backoffState{}
+		obj.backoff[target] = state
+	}
+	state.failures++
+	state.nextAttempt = obj.clock.Now().Add(state.delay())
+	return err
+}
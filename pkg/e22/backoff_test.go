@@ -0,0 +1,96 @@
+package e22
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mbalug7/go-ebyte-lora/pkg/hal"
+)
+
+func TestSendFixedReliableBackoff(t *testing.T) {
+	clock := hal.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	module, hw := newTestModule(t, nil, WithClock(clock))
+	module.setTransmissionMethod(TRANSMISSION_FIXED)
+	writeErr := errors.New("write failed")
+	hw.setWriteErr(writeErr)
+
+	send := func() error {
+		return module.SendFixedReliable(0x00, 0x01, 0x17, "hello")
+	}
+	if err := send(); !errors.Is(err, writeErr) {
+		t.Fatalf("expected write error, got: %v", err)
+	}
+
+	// first failure rejects sends for the initial delay
+	err := send()
+	if !errors.Is(err, ErrTargetBackoff) || !strings.Contains(err.Error(), "retry in 500ms") {
+		t.Fatalf("expected backoff with 500ms left, got: %v", err)
+	}
+	clock.Advance(499 * time.Millisecond)
+	err = send()
+	if !errors.Is(err, ErrTargetBackoff) || !strings.Contains(err.Error(), "retry in 1ms") {
+		t.Fatalf("expected backoff with 1ms left, got: %v", err)
+	}
+	if hw.writeCount() != 1 {
+		t.Fatalf("%d writes during backoff, expected 1", hw.writeCount())
+	}
+
+	// second failure doubles the delay
+	clock.Advance(time.Millisecond)
+	if err := send(); !errors.Is(err, writeErr) {
+		t.Fatalf("expected write error, got: %v", err)
+	}
+	clock.Advance(999 * time.Millisecond)
+	if err := send(); !errors.Is(err, ErrTargetBackoff) {
+		t.Fatalf("expected backoff after the second failure, got: %v", err)
+	}
+
+	// success resets the backoff
+	hw.setWriteErr(nil)
+	clock.Advance(time.Millisecond)
+	if err := send(); err != nil {
+		t.Fatalf("send failed after backoff: %v", err)
+	}
+	hw.setWriteErr(writeErr)
+	if err := send(); !errors.Is(err, writeErr) {
+		t.Fatalf("expected write error right after success, got: %v", err)
+	}
+	if hw.writeCount() != 4 {
+		t.Fatalf("%d writes, expected 4", hw.writeCount())
+	}
+}
+
+func TestSendFixedReliableCallerErrorsDontBackOff(t *testing.T) {
+	clock := hal.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	module, hw := newTestModule(t, nil, WithClock(clock), WithMaxPayloadSize(4))
+	module.setTransmissionMethod(TRANSMISSION_FIXED)
+
+	tests := map[string]func() error{
+		"payload too long": func() error { return module.SendFixedReliable(0x00, 0x01, 0x17, "hello") },
+		"empty payload":    func() error { return module.SendFixedReliable(0x00, 0x01, 0x17, "") },
+		"wrong mode": func() error {
+			hw.SetMode(hal.ModeSleep)
+			defer hw.SetMode(hal.ModeNormal)
+			return module.SendFixedReliable(0x00, 0x01, 0x17, "hi")
+		},
+		"closed port": func() error {
+			hw.setWriteErr(os.ErrClosed)
+			defer hw.setWriteErr(nil)
+			return module.SendFixedReliable(0x00, 0x01, 0x17, "hi")
+		},
+	}
+	for name, send := range tests {
+		if err := send(); err == nil || errors.Is(err, ErrTargetBackoff) {
+			t.Fatalf("%s: expected the send error, got: %v", name, err)
+		}
+		if err := send(); err == nil || errors.Is(err, ErrTargetBackoff) {
+			t.Fatalf("%s: target is backing off after a caller error: %v", name, err)
+		}
+	}
+	if err := module.SendFixedReliable(0x00, 0x01, 0x17, "hi"); err != nil {
+		t.Fatalf("send failed after caller errors: %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+package e22
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// backupCryptNote is stored in every backup file, crypt registers are write-only and can't be read from the chip
+const backupCryptNote = "crypt key is not included, it can't be read from the module. Add crypt_high and crypt_low to config, " +
+	"or set it again with ConfigBuilder.Crypt after restore"
+
+// configBackup defines backup file content, config is stored in the MarshalConfigJSON format
+type configBackup struct {
+	Note   string          `json:"note"`
+	Config json.RawMessage `json:"config"`
+}
+
+// BackupTo reads current config from the chip and stores it to the given file, in the MarshalConfigJSON format.
+// Crypt key can't be backed up, because the module doesn't allow reading it
+func (obj *Module) BackupTo(path string) error {
+	err := obj.ReadConfigFromChip()
+	if err != nil {
+		return fmt.Errorf("failed to backup config: %w", err)
+	}
+	config, err := obj.MarshalConfigJSON()
+	if err != nil {
+		return fmt.Errorf("failed to encode config backup: %w", err)
+	}
+	data, err := json.MarshalIndent(configBackup{Note: backupCryptNote, Config: config}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode config backup: %w", err)
+	}
+	err = os.WriteFile(path, data, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write config backup: %w", err)
+	}
+	return nil
+}
+
+// RestoreFrom permanently writes config stored by BackupTo to the chip, nothing is written if the chip already has it
+// or if the backup holds an invalid config.
+// Crypt key is not part of the backup, if the module used encryption, re-supply the key with crypt_high and crypt_low
+// in the backup config, or with ConfigBuilder.Crypt
+func (obj *Module) RestoreFrom(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config backup: %w", err)
+	}
+	var backup configBackup
+	err = json.Unmarshal(data, &backup)
+	if err != nil {
+		return fmt.Errorf("failed to decode config backup: %w", err)
+	}
+	builder := NewConfigBuilder(obj)
+	err = builder.FromJSON(backup.Config)
+	if err != nil {
+		return fmt.Errorf("failed to decode config backup: %w", err)
+	}
+	// backup file can be corrupted or edited by hand, it is validated before anything is written
+	_, err = builder.writeValidated(false)
+	if err != nil {
+		return fmt.Errorf("failed to restore config: %w", err)
+	}
+	return nil
+}
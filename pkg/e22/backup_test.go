@@ -0,0 +1,75 @@
+package e22
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeBackup stores the given JSON config to a backup file in a temporary directory
+func writeBackup(t *testing.T, config configJSON) string {
+	t.Helper()
+	raw, err := json.Marshal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(configBackup{Note: backupCryptNote, Config: raw})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "backup.json")
+	err = os.WriteFile(path, data, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// moduleConfigJSON returns config of the module in the MarshalConfigJSON format
+func moduleConfigJSON(t *testing.T, module *Module) configJSON {
+	t.Helper()
+	data, err := module.MarshalConfigJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var config configJSON
+	err = json.Unmarshal(data, &config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config
+}
+
+func TestRestoreFromRejectsInvalidChannel(t *testing.T) {
+	module, hw := newTestModule(t, nil)
+	config := moduleConfigJSON(t, module)
+	config.Channel = 0x60
+
+	err := module.RestoreFrom(writeBackup(t, config))
+	if err == nil {
+		t.Fatal("backup with channel 0x60 is restored")
+	}
+	if hw.writeCount() != 0 {
+		t.Fatalf("%d writes of an invalid backup, expected none", hw.writeCount())
+	}
+}
+
+func TestRestoreFromWritesBackupConfig(t *testing.T) {
+	module, hw := newTestModule(t, nil)
+	config := moduleConfigJSON(t, module)
+	config.Channel = 0x12
+	config.TransmissionMethod = "TRANSMISSION_FIXED"
+	hw.reads = [][]byte{{DefaultCommandSet.GetReg, REG2.ToByte(), 0x02, 0x12, 0x40}}
+
+	err := module.RestoreFrom(writeBackup(t, config))
+	if err != nil {
+		t.Fatalf("failed to restore backup: %v", err)
+	}
+	if hw.writeCount() != 1 {
+		t.Fatalf("%d writes, expected a single config write", hw.writeCount())
+	}
+	if module.Channel() != 0x12 || module.TransmissionMethod() != TRANSMISSION_FIXED {
+		t.Fatalf("restored config is not applied: %s", module.ConfigHex())
+	}
+}
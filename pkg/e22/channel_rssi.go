@@ -0,0 +1,107 @@
+package e22
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mbalug7/go-ebyte-lora/pkg/hal"
+)
+
+// rssiReplyTimeout time in which the chip must respond to the RSSI read command
+const rssiReplyTimeout = 1 * time.Second
+
+// AmbientRSSIdBm converts raw ambient noise RSSI returned by ReadChannelRSSI to dBm, -(256 - RSSI)
+func AmbientRSSIdBm(raw uint8) int {
+	return rssiToDBm(raw)
+}
+
+// ReadChannelRSSI reads current ambient noise and RSSI of the last received packet, as raw values.
+// The chip answers the RSSI read command (C0 C1 C2 C3) only in ModeNormal and ModeWakeUp, so the module is switched
+// to ModeNormal for the read if needed, and the previous mode is restored.
+// RSSIAmbientNoiseState(RSSI_AMBIENT_NOISE_ENABLE) must be set on the chip. Returns ErrUnsupportedFirmware if the
+// firmware is older than the version set for FeatureChannelRSSI
+func (obj *Module) ReadChannelRSSI() (ambient uint8, lastPacket uint8, err error) {
+	err = obj.checkNotObserver()
+	if err != nil {
+		return 0, 0, err
+	}
+	if obj.currentRegisters()[REG1].(*Reg1).ambientNoiseRSSI != RSSI_AMBIENT_NOISE_ENABLE {
+		return 0, 0, fmt.Errorf("ambient noise RSSI is not enabled, set RSSIAmbientNoiseState(RSSI_AMBIENT_NOISE_ENABLE)")
+	}
+	err = obj.requireFirmware(FeatureChannelRSSI)
+	if err != nil {
+		return 0, 0, err
+	}
+	obj.muSend.Lock()
+	defer obj.muSend.Unlock()
+
+	mode, err := obj.hw.GetMode()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get chip mode: %w", err)
+	}
+	if mode != hal.ModeNormal && mode != hal.ModeWakeUp {
+		err = obj.hw.SetMode(hal.ModeNormal)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to set chip mode for RSSI read: %w", err)
+		}
+		defer func() {
+			restoreErr := obj.hw.SetMode(mode)
+			if restoreErr != nil && err == nil {
+				err = fmt.Errorf("failed to restore chip mode after RSSI read: %w", restoreErr)
+			}
+		}()
+	}
+
+	return obj.queryChannelRSSI()
+}
+
+// queryChannelRSSI sends the RSSI read command and waits for the response, the chip must be in ModeNormal or
+// ModeWakeUp. Caller must hold muSend
+func (obj *Module) queryChannelRSSI() (ambient uint8, lastPacket uint8, err error) {
+	reply := make(chan []byte, 1)
+	obj.muRSSIReply.Lock()
+	obj.rssiReply = reply
+	obj.muRSSIReply.Unlock()
+	defer func() {
+		obj.muRSSIReply.Lock()
+		obj.rssiReply = nil
+		obj.muRSSIReply.Unlock()
+	}()
+
+	// read 2 registers from the address 0x00, ambient noise and the last packet RSSI
+	err = obj.hw.WriteSerial([]byte{0xC0, 0xC1, 0xC2, 0xC3, 0x00, 0x02})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to write RSSI read command: %w", err)
+	}
+	var data []byte
+	select {
+	case data = <-reply:
+	case <-obj.clock.After(rssiReplyTimeout):
+		// response didn't trigger the reception, read it directly
+		data, err = obj.hw.ReadSerial()
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to read RSSI response: %w", err)
+		}
+	}
+	if !isRSSIReply(data, obj.commands.GetReg) {
+		return 0, 0, fmt.Errorf("%w: RSSI response % X", ErrInvalidConfigResponse, data)
+	}
+	return data[3], data[4], nil
+}
+
+// isRSSIReply returns true if the data is the response to the RSSI read command, C1 00 02 followed by two values
+func isRSSIReply(data []byte, command byte) bool {
+	return len(data) >= 5 && data[0] == command && data[1] == 0x00 && data[2] == 0x02
+}
+
+// offerRSSIReply passes received data to the pending ReadChannelRSSI call, returns false if the data is not expected
+func (obj *Module) offerRSSIReply(data []byte) bool {
+	obj.muRSSIReply.Lock()
+	defer obj.muRSSIReply.Unlock()
+	if obj.rssiReply == nil || !isRSSIReply(data, obj.commands.GetReg) {
+		return false
+	}
+	obj.rssiReply <- data
+	obj.rssiReply = nil
+	return true
+}
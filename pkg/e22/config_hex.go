@@ -0,0 +1,54 @@
+package e22
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/mbalug7/go-ebyte-lora/pkg/hal"
+)
+
+// ConfigHex returns all register bytes as a single hex string, starting with ADD_H, e.g. "0003626200000000".
+// Crypt registers are write only, they are always reported as 00
+func (obj *Module) ConfigHex() string {
+	registers := obj.currentRegisters()
+	data := make([]byte, len(registers))
+	for i, reg := range registers {
+		data[i] = reg.GetValue()
+	}
+	return strings.ToUpper(hex.EncodeToString(data))
+}
+
+// unsupportedBits register bits that are not part of the register model, they would be dropped when the value is set
+var unsupportedBits = map[hal.RegAddress]uint8{
+	REG1: 0x1C,
+	REG3: 0x30,
+}
+
+// LoadConfigHex stages register values from the string returned by ConfigHex. Readable registers only
+// (without crypt) are accepted too. Crypt key 0000 means that the crypt key is not changed, other keys are staged
+// like with Crypt. Channel above 80, or bits that are not supported by the register model, fail the write like Channel
+func (obj *ConfigBuilder) LoadConfigHex(config string) error {
+	data, err := hex.DecodeString(strings.TrimSpace(config))
+	if err != nil {
+		return fmt.Errorf("failed to decode config hex: %w", err)
+	}
+	if len(data) != int(CRYPT_H) && len(data) != len(obj.stagedRegisters) {
+		return fmt.Errorf("config hex must hold %d or %d bytes, got %d", CRYPT_H, len(obj.stagedRegisters), len(data))
+	}
+	for i, value := range data {
+		address := hal.RegAddress(i)
+		if address == REG2 {
+			obj.Channel(value)
+			continue
+		}
+		if value&unsupportedBits[address] != 0 {
+			obj.stageErr(fmt.Errorf("register 0x%02X value 0x%02X sets unsupported bits 0x%02X", i, value, value&unsupportedBits[address]))
+		}
+		obj.stagedRegisters[address].SetValue(value)
+	}
+	if len(data) > int(CRYPT_L) && (data[CRYPT_H] != 0 || data[CRYPT_L] != 0) {
+		obj.cryptStaged = true
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package e22
+
+import (
+	"testing"
+)
+
+func TestLoadConfigHexStagesCrypt(t *testing.T) {
+	module, _ := newTestModule(t, nil)
+	tests := []struct {
+		config string
+		crypt  bool
+	}{
+		{config: "0003626200001234", crypt: true},
+		{config: "0003626200000000", crypt: false},
+		{config: "000362620000", crypt: false},
+	}
+	for _, test := range tests {
+		builder := NewConfigBuilder(module)
+		err := builder.LoadConfigHex(test.config)
+		if err != nil {
+			t.Fatalf("failed to load %s: %v", test.config, err)
+		}
+		if builder.cryptStaged != test.crypt {
+			t.Fatalf("%s: crypt staged is %t, expected %t", test.config, builder.cryptStaged, test.crypt)
+		}
+		cryptChanges := 0
+		for _, change := range builder.Diff() {
+			if change.Address == CRYPT_H || change.Address == CRYPT_L {
+				cryptChanges++
+			}
+		}
+		if test.crypt && cryptChanges != 2 {
+			t.Fatalf("%s: diff has %d crypt changes, expected 2", test.config, cryptChanges)
+		}
+		if !test.crypt && cryptChanges != 0 {
+			t.Fatalf("%s: diff has %d crypt changes, expected none", test.config, cryptChanges)
+		}
+	}
+}
+
+func TestLoadConfigHexRejectsInvalidValues(t *testing.T) {
+	module, _ := newTestModule(t, nil)
+	tests := map[string]string{
+		"channel 0x60":          "0003626260000000",
+		"REG1 unsupported bits": "0003627E12000000",
+		"REG3 unsupported bits": "0003626212300000",
+	}
+	for name, config := range tests {
+		builder := NewConfigBuilder(module)
+		err := builder.LoadConfigHex(config)
+		if err != nil {
+			t.Fatalf("%s: failed to load %s: %v", name, config, err)
+		}
+		if err := builder.Validate(); err == nil {
+			t.Fatalf("%s: %s passed validation", name, config)
+		}
+		if err := builder.WriteTemporaryConfig(); err == nil {
+			t.Fatalf("%s: %s is written", name, config)
+		}
+	}
+
+	builder := NewConfigBuilder(module)
+	err := builder.LoadConfigHex("0003626250000000")
+	if err != nil {
+		t.Fatalf("failed to load channel 0x50: %v", err)
+	}
+	if err := builder.Validate(); err != nil {
+		t.Fatalf("channel 0x50 failed validation: %v", err)
+	}
+}
@@ -0,0 +1,207 @@
+package e22
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// configJSON JSON form of the module config, enum values are stored as constant names
+type configJSON struct {
+	AddressHigh        uint8  `json:"address_high"`
+	AddressLow         uint8  `json:"address_low"`
+	BaudRate           string `json:"baud_rate"`
+	Parity             string `json:"parity"`
+	AirDataRate        string `json:"air_data_rate"`
+	SubPacket          string `json:"sub_packet"`
+	AmbientNoiseRSSI   string `json:"ambient_noise_rssi"`
+	TransmittingPower  string `json:"transmitting_power"`
+	Channel            uint8  `json:"channel"`
+	RSSI               string `json:"rssi"`
+	TransmissionMethod string `json:"transmission_method"`
+	LBT                string `json:"lbt"`
+	WORCycle           string `json:"wor_cycle"`
+	Crypt              string `json:"crypt"` // "set" or "none", see Module.CryptKeySet
+	CryptHigh          *uint8 `json:"crypt_high,omitempty"`
+	CryptLow           *uint8 `json:"crypt_low,omitempty"`
+}
+
+// crypt field values, the same as the CRYPT line of Module.GetModuleConfiguration
+const (
+	cryptSet  = "set"
+	cryptNone = "none"
+)
+
+// enumName name of the register constant
+type enumName struct {
+	name  string
+	value uint8
+}
+
+var (
+	baudRateNames = []enumName{
+		{"BAUD_1200", uint8(BAUD_1200)}, {"BAUD_2400", uint8(BAUD_2400)}, {"BAUD_4800", uint8(BAUD_4800)},
+		{"BAUD_9600", uint8(BAUD_9600)}, {"BAUD_19200", uint8(BAUD_19200)}, {"BAUD_38400", uint8(BAUD_38400)},
+		{"BAUD_57600", uint8(BAUD_57600)}, {"BAUD_115200", uint8(BAUD_115200)},
+	}
+	parityNames = []enumName{
+		{"PARITY_8N1", uint8(PARITY_8N1)}, {"PARITY_8O1", uint8(PARITY_8O1)}, {"PARITY_8E1", uint8(PARITY_8E1)},
+	}
+	airDataRateNames = []enumName{
+		{"ADR_2400_0", uint8(ADR_2400_0)}, {"ADR_2400_1", uint8(ADR_2400_1)}, {"ADR_2400", uint8(ADR_2400)},
+		{"ADR_4800", uint8(ADR_4800)}, {"ADR_9600", uint8(ADR_9600)}, {"ADR_19200", uint8(ADR_19200)},
+		{"ADR_38400", uint8(ADR_38400)}, {"ADR_62500", uint8(ADR_62500)},
+	}
+	subPacketNames = []enumName{
+		{"BYTES_200", uint8(BYTES_200)}, {"BYTES_128", uint8(BYTES_128)}, {"BYTES_64", uint8(BYTES_64)},
+		{"BYTES_32", uint8(BYTES_32)},
+	}
+	ambientNoiseNames = []enumName{
+		{"RSSI_AMBIENT_NOISE_DISABLE", uint8(RSSI_AMBIENT_NOISE_DISABLE)},
+		{"RSSI_AMBIENT_NOISE_ENABLE", uint8(RSSI_AMBIENT_NOISE_ENABLE)},
+	}
+	transmittingPowerNames = []enumName{
+		{"TP_22_DBM", uint8(TP_22_DBM)}, {"TP_17_DBM", uint8(TP_17_DBM)}, {"TP_13_DBM", uint8(TP_13_DBM)},
+		{"TP_10_DBM", uint8(TP_10_DBM)},
+	}
+	rssiNames = []enumName{
+		{"RSSI_DISABLE", uint8(RSSI_DISABLE)}, {"RSSI_ENABLE", uint8(RSSI_ENABLE)},
+	}
+	transmissionMethodNames = []enumName{
+		{"TRANSMISSION_TRANSPARENT", uint8(TRANSMISSION_TRANSPARENT)}, {"TRANSMISSION_FIXED", uint8(TRANSMISSION_FIXED)},
+	}
+	lbtNames = []enumName{
+		{"LBT_DISABLE", uint8(LBT_DISABLE)}, {"LBT_ENABLE", uint8(LBT_ENABLE)},
+	}
+	worCycleNames = []enumName{
+		{"WOR_500_MS", uint8(WOR_500_MS)}, {"WOR_1000_MS", uint8(WOR_1000_MS)}, {"WOR_1500_MS", uint8(WOR_1500_MS)},
+		{"WOR_2000_MS", uint8(WOR_2000_MS)}, {"WOR_2500_MS", uint8(WOR_2500_MS)}, {"WOR_3000_MS", uint8(WOR_3000_MS)},
+		{"WOR_3500_MS", uint8(WOR_3500_MS)}, {"WOR_4000_MS", uint8(WOR_4000_MS)},
+	}
+)
+
+// nameOf returns name of the constant with the given value, the value itself if there is no such constant
+func nameOf(names []enumName, value uint8) string {
+	for _, n := range names {
+		if n.value == value {
+			return n.name
+		}
+	}
+	return fmt.Sprintf("0x%02X", value)
+}
+
+// isKnownValue returns true if value is one of the constants
+func isKnownValue(names []enumName, value uint8) bool {
+	for _, n := range names {
+		if n.value == value {
+			return true
+		}
+	}
+	return false
+}
+
+// valueOf returns value of the constant with the given name
+func valueOf(names []enumName, field string, name string) (uint8, error) {
+	for _, n := range names {
+		if n.name == name {
+			return n.value, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown %s value %q", field, name)
+}
+
+// MarshalConfigJSON returns config that was last read from the chip as JSON, enum values are written as constant
+// names (e.g. "air_data_rate": "ADR_9600"). Crypt key is write only, the crypt field only reports whether a key
+// was set by this module instance ("set" or "none"), like GetModuleConfiguration. Stage it on other modules with
+// ConfigBuilder.FromJSON
+func (obj *Module) MarshalConfigJSON() ([]byte, error) {
+	registers := obj.currentRegisters()
+	reg0 := registers[REG0].(*Reg0)
+	reg1 := registers[REG1].(*Reg1)
+	reg3 := registers[REG3].(*Reg3)
+	parity := reg0.parityBit
+	if parity == 0x18 { // datasheet defines 11 as 8N1, the same as 00
+		parity = PARITY_8N1
+	}
+	crypt := cryptNone
+	if obj.CryptKeySet() {
+		crypt = cryptSet
+	}
+	return json.MarshalIndent(configJSON{
+		AddressHigh:        registers[ADD_H].GetValue(),
+		AddressLow:         registers[ADD_L].GetValue(),
+		BaudRate:           nameOf(baudRateNames, uint8(reg0.baudRate)),
+		Parity:             nameOf(parityNames, uint8(parity)),
+		AirDataRate:        nameOf(airDataRateNames, uint8(reg0.adRate)),
+		SubPacket:          nameOf(subPacketNames, uint8(reg1.subPacket)),
+		AmbientNoiseRSSI:   nameOf(ambientNoiseNames, uint8(reg1.ambientNoiseRSSI)),
+		TransmittingPower:  nameOf(transmittingPowerNames, uint8(reg1.transmittingPower)),
+		Channel:            registers[REG2].GetValue(),
+		RSSI:               nameOf(rssiNames, uint8(reg3.enableRSSI)),
+		TransmissionMethod: nameOf(transmissionMethodNames, uint8(reg3.transmissionMethod)),
+		LBT:                nameOf(lbtNames, uint8(reg3.lbtEnable)),
+		WORCycle:           nameOf(worCycleNames, uint8(reg3.worCycle)),
+		Crypt:              crypt,
+	}, "", "  ")
+}
+
+// FromJSON stages every field of the JSON config returned by Module.MarshalConfigJSON. Crypt key is staged only
+// if both crypt_high and crypt_low are set, "crypt": "set" alone doesn't change the key, since the key itself
+// can't be read from the chip. Nothing is staged if any field is invalid
+func (obj *ConfigBuilder) FromJSON(data []byte) error {
+	var config configJSON
+	err := json.Unmarshal(data, &config)
+	if err != nil {
+		return fmt.Errorf("failed to decode config JSON: %w", err)
+	}
+	fields := []struct {
+		names []enumName
+		field string
+		name  string
+	}{
+		{names: baudRateNames, field: "baud_rate", name: config.BaudRate},
+		{names: parityNames, field: "parity", name: config.Parity},
+		{names: airDataRateNames, field: "air_data_rate", name: config.AirDataRate},
+		{names: subPacketNames, field: "sub_packet", name: config.SubPacket},
+		{names: ambientNoiseNames, field: "ambient_noise_rssi", name: config.AmbientNoiseRSSI},
+		{names: transmittingPowerNames, field: "transmitting_power", name: config.TransmittingPower},
+		{names: rssiNames, field: "rssi", name: config.RSSI},
+		{names: transmissionMethodNames, field: "transmission_method", name: config.TransmissionMethod},
+		{names: lbtNames, field: "lbt", name: config.LBT},
+		{names: worCycleNames, field: "wor_cycle", name: config.WORCycle},
+	}
+	values := make([]uint8, len(fields))
+	for i, f := range fields {
+		values[i], err = valueOf(f.names, f.field, f.name)
+		if err != nil {
+			return err
+		}
+	}
+	if config.Channel > 80 {
+		return fmt.Errorf("channel %d is outside of the channel range 0-80", config.Channel)
+	}
+	if (config.CryptHigh == nil) != (config.CryptLow == nil) {
+		return fmt.Errorf("crypt_high and crypt_low must be set together")
+	}
+	switch config.Crypt {
+	case "", cryptSet, cryptNone:
+	default:
+		return fmt.Errorf("unknown crypt value %q, expected %q or %q", config.Crypt, cryptSet, cryptNone)
+	}
+
+	obj.Address(config.AddressHigh, config.AddressLow).
+		SerialBaudRate(BaudRate(values[0])).
+		SerialParityBit(Parity(values[1])).
+		AirDataRate(AirDataRate(values[2])).
+		SubPacketLength(SubPacket(values[3])).
+		RSSIAmbientNoiseState(RSSIAmbientNoise(values[4])).
+		TransmittingPower(TransmittingPower(values[5])).
+		Channel(config.Channel).
+		RSSIState(EnableRSSI(values[6])).
+		TransmissionMethod(TransmissionMethod(values[7])).
+		LBTState(LBT(values[8])).
+		WORCycle(WORCycle(values[9]))
+	if config.CryptHigh != nil {
+		obj.Crypt(*config.CryptHigh, *config.CryptLow)
+	}
+	return nil
+}
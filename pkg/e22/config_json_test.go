@@ -0,0 +1,54 @@
+package e22
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestConfigJSONRoundTripWithCrypt(t *testing.T) {
+	module, _ := newTestModule(t, nil)
+	module.muRegisters.Lock()
+	module.registers[REG2].SetValue(0x17)
+	module.registers[REG3].SetValue(uint8(RSSI_ENABLE) | uint8(WOR_1500_MS))
+	module.cryptKeySet = true
+	module.muRegisters.Unlock()
+
+	data, err := module.MarshalConfigJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var config configJSON
+	err = json.Unmarshal(data, &config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Crypt != cryptSet {
+		t.Fatalf("crypt is %q, expected %q", config.Crypt, cryptSet)
+	}
+
+	other, _ := newTestModule(t, nil)
+	builder := NewConfigBuilder(other)
+	err = builder.FromJSON(data)
+	if err != nil {
+		t.Fatalf("failed to stage marshaled config: %v", err)
+	}
+	if builder.cryptStaged {
+		t.Fatal("crypt key is staged without crypt_high and crypt_low")
+	}
+	current := module.currentRegisters()
+	for i := ADD_H; i < CRYPT_H; i++ {
+		if builder.stagedRegisters[i].GetValue() != current[i].GetValue() {
+			t.Fatalf("register 0x%02X staged as 0x%02X, expected 0x%02X", i, builder.stagedRegisters[i].GetValue(), current[i].GetValue())
+		}
+	}
+
+	config.Crypt = "unknown"
+	data, err = json.Marshal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = NewConfigBuilder(other).FromJSON(data)
+	if err == nil {
+		t.Fatal("unknown crypt value is accepted")
+	}
+}
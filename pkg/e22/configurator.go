@@ -1,18 +1,47 @@
 package e22
 
+import (
+	"fmt"
+	"math"
+
+	"github.com/mbalug7/go-ebyte-lora/pkg/hal"
+)
+
 // ConfigBuilder object that is used to build eByte E22 config
 // it is possible to reconfigure only one  parameter
 type ConfigBuilder struct {
 	chip            *Module
 	stagedRegisters registersCollection
+	err             error // first staging error, returned on write
+	cryptStaged     bool  // Crypt was called
 }
 
 // NewConfigBuilder constructs ConfigBuilder
 func NewConfigBuilder(chip *Module) *ConfigBuilder {
 	return &ConfigBuilder{
 		chip:            chip,
-		stagedRegisters: chip.registers.Copy(), // copy current values
+		stagedRegisters: chip.currentRegisters(), // copy current values
+	}
+}
+
+// CopyConfigFrom returns ConfigBuilder for this module with the config of src staged, the config that was last read
+// from src chip is used. Nothing is written, call a write method of the returned builder to apply it.
+// Crypt key can't be read back from the chip, it is not copied and must be staged separately with Crypt
+func (obj *Module) CopyConfigFrom(src *Module) (*ConfigBuilder, error) {
+	if src == nil {
+		return nil, fmt.Errorf("source module is nil")
+	}
+	if src.variant.Name != obj.variant.Name {
+		return nil, fmt.Errorf("can't copy config of %s module to %s module", src.variant.Name, obj.variant.Name)
 	}
+	builder := NewConfigBuilder(obj)
+	for i, reg := range src.currentRegisters() {
+		if reg.GetAddress() >= CRYPT_H {
+			continue
+		}
+		builder.stagedRegisters[i].SetValue(reg.GetValue())
+	}
+	return builder, nil
 }
 
 // Address set module address
@@ -24,23 +53,28 @@ func (obj *ConfigBuilder) Address(addressHigh uint8, addressLow uint8) *ConfigBu
 	return obj
 }
 
+// Address16 set module address as a single 16 bit value, e.g. 0x0003 sets ADD_H to 0x00 and ADD_L to 0x03
+func (obj *ConfigBuilder) Address16(address uint16) *ConfigBuilder {
+	return obj.Address(uint8(address>>8), uint8(address))
+}
+
 // REG0 params
 // SerialBaudRate set module baud rate
-func (obj *ConfigBuilder) SerialBaudRate(br baudRate) *ConfigBuilder {
+func (obj *ConfigBuilder) SerialBaudRate(br BaudRate) *ConfigBuilder {
 	reg0 := obj.stagedRegisters[REG0].(*Reg0)
 	reg0.baudRate = br
 	return obj
 }
 
 // SerialParityBit set module serial parity bit
-func (obj *ConfigBuilder) SerialParityBit(parityBit parity) *ConfigBuilder {
+func (obj *ConfigBuilder) SerialParityBit(parityBit Parity) *ConfigBuilder {
 	reg0 := obj.stagedRegisters[REG0].(*Reg0)
 	reg0.parityBit = parityBit
 	return obj
 }
 
 // AirDataRate module data rate
-func (obj *ConfigBuilder) AirDataRate(adRate airDataRate) *ConfigBuilder {
+func (obj *ConfigBuilder) AirDataRate(adRate AirDataRate) *ConfigBuilder {
 	reg0 := obj.stagedRegisters[REG0].(*Reg0)
 	reg0.adRate = adRate
 	return obj
@@ -48,21 +82,21 @@ func (obj *ConfigBuilder) AirDataRate(adRate airDataRate) *ConfigBuilder {
 
 // REG1 params
 // SubPacketLength set module data packet length
-func (obj *ConfigBuilder) SubPacketLength(subPacketLength subPacket) *ConfigBuilder {
+func (obj *ConfigBuilder) SubPacketLength(subPacketLength SubPacket) *ConfigBuilder {
 	reg1 := obj.stagedRegisters[REG1].(*Reg1)
 	reg1.subPacket = subPacketLength
 	return obj
 }
 
 // RSSIAmbientNoiseState set rssi ambient noise state
-func (obj *ConfigBuilder) RSSIAmbientNoiseState(state rssiAmbientNoise) *ConfigBuilder {
+func (obj *ConfigBuilder) RSSIAmbientNoiseState(state RSSIAmbientNoise) *ConfigBuilder {
 	reg1 := obj.stagedRegisters[REG1].(*Reg1)
 	reg1.ambientNoiseRSSI = state
 	return obj
 }
 
 // TransmittingPower set transmitting power
-func (obj *ConfigBuilder) TransmittingPower(power transmittingPower) *ConfigBuilder {
+func (obj *ConfigBuilder) TransmittingPower(power TransmittingPower) *ConfigBuilder {
 	reg1 := obj.stagedRegisters[REG1].(*Reg1)
 	reg1.transmittingPower = power
 	return obj
@@ -70,58 +104,204 @@ func (obj *ConfigBuilder) TransmittingPower(power transmittingPower) *ConfigBuil
 
 // REG2 params
 
-// Channel sets chip channel, range 0-80, Actual frequency = 850.125 + CH *1M
+// Channel sets chip channel, range 0-80, Actual frequency = 850.125 + CH *1M. Channel above 80 fails the write
 func (obj *ConfigBuilder) Channel(channel uint8) *ConfigBuilder {
+	// chip supports 80 channels, set value would clamp the channel to 80
+	if channel > 80 {
+		obj.stageErr(fmt.Errorf("channel %d is outside of the channel range 0-80", channel))
+		return obj
+	}
 	reg2 := obj.stagedRegisters[REG2].(*Reg2)
-	// set value limits max value to 80 -> chip supports 80 channels
 	reg2.SetValue(channel)
 	return obj
 }
 
+// Frequency sets chip channel from the channel center frequency in MHz, channel = frequency - base frequency.
+// Frequency that is not on the 1 MHz channel grid, or that is outside of the channel range, fails the write
+func (obj *ConfigBuilder) Frequency(mhz float64) *ConfigBuilder {
+	offset := mhz - obj.chip.baseFrequency
+	channel := math.Round(offset)
+	if math.Abs(offset-channel) > 0.001 {
+		obj.stageErr(fmt.Errorf("frequency %.3f MHz is not on the channel grid, base frequency is %.3f MHz", mhz, obj.chip.baseFrequency))
+		return obj
+	}
+	if channel < 0 || channel > 80 {
+		obj.stageErr(fmt.Errorf("frequency %.3f MHz maps to channel %.0f, outside of the channel range 0-80", mhz, channel))
+		return obj
+	}
+	return obj.Channel(uint8(channel))
+}
+
 // REG 3
 // RSSIState enable rssi value in received message
-func (obj *ConfigBuilder) RSSIState(state enableRSSI) *ConfigBuilder {
+func (obj *ConfigBuilder) RSSIState(state EnableRSSI) *ConfigBuilder {
 	reg3 := obj.stagedRegisters[REG3].(*Reg3)
 	reg3.enableRSSI = state
 	return obj
 }
 
 // TransmissionMethod select transparent or fixed method
-func (obj *ConfigBuilder) TransmissionMethod(method transmissionMethod) *ConfigBuilder {
+func (obj *ConfigBuilder) TransmissionMethod(method TransmissionMethod) *ConfigBuilder {
 	reg3 := obj.stagedRegisters[REG3].(*Reg3)
 	reg3.transmissionMethod = method
 	return obj
 }
 
 // LBTState set lbt state
-func (obj *ConfigBuilder) LBTState(state lbt) *ConfigBuilder {
+func (obj *ConfigBuilder) LBTState(state LBT) *ConfigBuilder {
 	reg3 := obj.stagedRegisters[REG3].(*Reg3)
 	reg3.lbtEnable = state
 	return obj
 }
 
 // set wake on receive cycle
-func (obj *ConfigBuilder) WORCycle(wor worCycle) *ConfigBuilder {
+func (obj *ConfigBuilder) WORCycle(wor WORCycle) *ConfigBuilder {
 	reg3 := obj.stagedRegisters[REG3].(*Reg3)
 	reg3.worCycle = wor
 	return obj
 }
 
+// FullConfig stages all params of the given config, crypt key is not part of FullConfig and is not changed.
+// Values are not checked until Validate or a write method is called
+func (obj *ConfigBuilder) FullConfig(config FullConfig) *ConfigBuilder {
+	return obj.Address(config.Address.High, config.Address.Low).
+		SerialBaudRate(config.SerialParams.BaudRate).
+		SerialParityBit(config.SerialParams.Parity).
+		AirDataRate(config.RadioParams.AirDataRate).
+		SubPacketLength(config.RadioParams.SubPacket).
+		RSSIAmbientNoiseState(config.RadioParams.AmbientNoiseRSSI).
+		TransmittingPower(config.RadioParams.TransmittingPower).
+		Channel(config.RadioParams.Channel).
+		RSSIState(config.TransmissionParams.RSSI).
+		TransmissionMethod(config.TransmissionParams.Method).
+		LBTState(config.TransmissionParams.LBT).
+		WORCycle(config.TransmissionParams.WORCycle)
+}
+
 // Crypt set encryption key that is not readable, make sure that other side uses the same key
 func (obj *ConfigBuilder) Crypt(cryptHigh uint8, cryptLow uint8) *ConfigBuilder {
 	cryptH := obj.stagedRegisters[CRYPT_H].(*CryptH)
 	cryptH.value = cryptHigh
 	cryptL := obj.stagedRegisters[CRYPT_L].(*CryptL)
 	cryptL.value = cryptLow
+	obj.cryptStaged = true
 	return obj
 }
 
 // WritePermanentConfig writes new config to the chip
 func (obj *ConfigBuilder) WritePermanentConfig() error {
-	return obj.chip.WriteConfigToChip(false, obj.stagedRegisters)
+	return obj.write(false)
 }
 
 // WriteTemporaryConfig writes new config to the chip but, on chip reboot config is lost
 func (obj *ConfigBuilder) WriteTemporaryConfig() error {
-	return obj.chip.WriteConfigToChip(true, obj.stagedRegisters)
+	return obj.write(true)
+}
+
+// write writes staged registers to the chip, staged config that is the same as the chip config is reported as error
+// unless the module is created with WithIdempotentWrites
+func (obj *ConfigBuilder) write(temporary bool) error {
+	changed, err := obj.writeValidated(temporary)
+	if err != nil {
+		return err
+	}
+	if !changed && !obj.chip.idempotentWrites {
+		return fmt.Errorf("new register setup is the same as the setup on the chip, ignoring")
+	}
+	return nil
+}
+
+// writeValidated validates staged registers and writes them to the chip, returns false if the chip already has them
+func (obj *ConfigBuilder) writeValidated(temporary bool) (bool, error) {
+	err := obj.Validate()
+	if err != nil {
+		return false, err
+	}
+	return obj.chip.WriteConfigToChip(temporary, obj.stagedRegisters)
+}
+
+// Validate checks staged config without accessing the chip. Staging errors, channel range, and values of
+// every register parameter are checked, a value that isn't one of the defined constants is rejected. Crypt key
+// 0x0000 is rejected, since the key is not written when both bytes are zero. Write methods call it before the write
+func (obj *ConfigBuilder) Validate() error {
+	if obj.err != nil {
+		return obj.err
+	}
+	if channel := obj.stagedRegisters[REG2].GetValue(); channel > 80 {
+		return fmt.Errorf("channel %d is outside of the channel range 0-80", channel)
+	}
+	reg0 := obj.stagedRegisters[REG0].(*Reg0)
+	if reg0.adRate.BPS() == 0 {
+		return fmt.Errorf("unknown air data rate 0x%02X", uint8(reg0.adRate))
+	}
+	reg1 := obj.stagedRegisters[REG1].(*Reg1)
+	if _, ok := subPacketSizeMap[reg1.subPacket]; !ok {
+		return fmt.Errorf("unknown sub-packet size 0x%02X", uint8(reg1.subPacket))
+	}
+	switch reg1.transmittingPower {
+	case TP_22_DBM, TP_17_DBM, TP_13_DBM, TP_10_DBM:
+	default:
+		return fmt.Errorf("unknown transmitting power 0x%02X", uint8(reg1.transmittingPower))
+	}
+	reg3 := obj.stagedRegisters[REG3].(*Reg3)
+	params := []struct {
+		names []enumName
+		field string
+		value uint8
+	}{
+		{names: baudRateNames, field: "baud rate", value: uint8(reg0.baudRate)},
+		{names: ambientNoiseNames, field: "ambient noise RSSI state", value: uint8(reg1.ambientNoiseRSSI)},
+		{names: rssiNames, field: "RSSI state", value: uint8(reg3.enableRSSI)},
+		{names: transmissionMethodNames, field: "transmission method", value: uint8(reg3.transmissionMethod)},
+		{names: lbtNames, field: "LBT state", value: uint8(reg3.lbtEnable)},
+		{names: worCycleNames, field: "WOR cycle", value: uint8(reg3.worCycle)},
+	}
+	for _, p := range params {
+		if !isKnownValue(p.names, p.value) {
+			return fmt.Errorf("unknown %s 0x%02X", p.field, p.value)
+		}
+	}
+	// datasheet defines 11 as 8N1, the same as 00
+	if reg0.parityBit != 0x18 && !isKnownValue(parityNames, uint8(reg0.parityBit)) {
+		return fmt.Errorf("unknown parity 0x%02X", uint8(reg0.parityBit))
+	}
+	if obj.cryptStaged && registerWriteValue(obj.stagedRegisters[CRYPT_H]) == 0 &&
+		registerWriteValue(obj.stagedRegisters[CRYPT_L]) == 0 {
+		return fmt.Errorf("crypt key 0x0000 is not written to the chip, the key on the chip can't be cleared")
+	}
+	return nil
+}
+
+// RegisterChange register value that is changed by the staged config
+type RegisterChange struct {
+	Address hal.RegAddress
+	Old     uint8
+	New     uint8
+}
+
+// Diff returns registers whose staged value differs from the config that was last read from the chip.
+// Crypt registers are write only, they are in the diff only if a key is staged with Crypt, Old value is 0 then
+func (obj *ConfigBuilder) Diff() []RegisterChange {
+	current := obj.chip.currentRegisters()
+	var changes []RegisterChange
+	for i, reg := range obj.stagedRegisters {
+		address := reg.GetAddress()
+		if address == CRYPT_H || address == CRYPT_L {
+			if obj.cryptStaged {
+				changes = append(changes, RegisterChange{Address: address, New: registerWriteValue(reg)})
+			}
+			continue
+		}
+		if reg.GetValue() != current[i].GetValue() {
+			changes = append(changes, RegisterChange{Address: address, Old: current[i].GetValue(), New: reg.GetValue()})
+		}
+	}
+	return changes
+}
+
+// stageErr saves the staging error, only the first one is kept
+func (obj *ConfigBuilder) stageErr(err error) {
+	if obj.err == nil {
+		obj.err = err
+	}
 }
@@ -0,0 +1,22 @@
+package e22
+
+import (
+	"testing"
+)
+
+func TestChannelOutOfRangeFailsValidation(t *testing.T) {
+	module, _ := newTestModule(t, nil)
+
+	builder := NewConfigBuilder(module).Channel(81)
+	if err := builder.Validate(); err == nil {
+		t.Fatal("channel 81 passed validation")
+	}
+	if channel := builder.stagedRegisters[REG2].GetValue(); channel != 0 {
+		t.Fatalf("channel 81 is staged as %d", channel)
+	}
+
+	builder = NewConfigBuilder(module).Channel(80)
+	if err := builder.Validate(); err != nil {
+		t.Fatalf("channel 80 failed validation: %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+package e22
+
+import "bytes"
+
+// recordTx saves the data that is written to the chip, if echo suppression is enabled
+func (obj *Module) recordTx(data []byte) {
+	if obj.echoWindow <= 0 {
+		return
+	}
+	obj.muStats.Lock()
+	defer obj.muStats.Unlock()
+	obj.lastTx = append(obj.lastTx[:0], data...)
+	obj.lastTxTime = obj.clock.Now()
+}
+
+// isEcho returns true and counts the echo if the received data is equal to the last written data, within echo window
+func (obj *Module) isEcho(msg []byte) bool {
+	if obj.echoWindow <= 0 {
+		return false
+	}
+	obj.muStats.Lock()
+	defer obj.muStats.Unlock()
+	if obj.lastTx == nil || obj.clock.Now().Sub(obj.lastTxTime) > obj.echoWindow || !bytes.Equal(msg, obj.lastTx) {
+		return false
+	}
+	// one write is echoed once
+	obj.lastTx = nil
+	obj.echoes++
+	return true
+}
+
+// EchoesSuppressed returns number of received frames that were dropped as an echo of the written data
+func (obj *Module) EchoesSuppressed() uint64 {
+	obj.muStats.Lock()
+	defer obj.muStats.Unlock()
+	return obj.echoes
+}
@@ -0,0 +1,225 @@
+package e22
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// PinConfig hardware params that are needed to construct common.HWHandler
+type PinConfig struct {
+	M0       int
+	M1       int
+	AUX      int
+	TTY      string
+	GPIOChip string
+}
+
+// DefaultPinConfig RPi 4 wiring that is used in the examples
+var DefaultPinConfig = PinConfig{
+	M0:       23,
+	M1:       24,
+	AUX:      25,
+	TTY:      "/dev/ttyS0",
+	GPIOChip: "gpiochip0",
+}
+
+// DefaultModuleConfig E22 factory config
+var DefaultModuleConfig = ModuleConfig{
+	SerialParams: SerialParams{
+		BaudRate: BAUD_9600,
+		Parity:   PARITY_8N1,
+	},
+	RadioParams: RadioParams{
+		AirDataRate:       ADR_2400,
+		SubPacket:         BYTES_200,
+		AmbientNoiseRSSI:  RSSI_AMBIENT_NOISE_DISABLE,
+		TransmittingPower: TP_22_DBM,
+		Channel:           0x12,
+	},
+	TransmissionParams: TransmissionParams{
+		RSSI:     RSSI_DISABLE,
+		Method:   TRANSMISSION_TRANSPARENT,
+		LBT:      LBT_DISABLE,
+		WORCycle: WOR_2000_MS,
+	},
+}
+
+// ConfigFromEnv reads module and pin config from environment variables, unset variables keep DefaultModuleConfig
+// and DefaultPinConfig values. Supported variables:
+//
+//	EBYTE_M0, EBYTE_M1, EBYTE_AUX     GPIO line offsets
+//	EBYTE_TTY, EBYTE_GPIO_CHIP        serial port and GPIO chip names
+//	EBYTE_ADDRESS                     16 bit module address, e.g. 0x0102
+//	EBYTE_CHANNEL                     channel 0-80
+//	EBYTE_BAUD                        serial baud rate, e.g. 9600
+//	EBYTE_PARITY                      8N1, 8O1 or 8E1
+//	EBYTE_AIR_RATE                    air data rate in bps, e.g. 2400
+//	EBYTE_SUB_PACKET                  sub-packet size in bytes, 32, 64, 128 or 200
+//	EBYTE_POWER                       transmitting power in dBm, 22, 17, 13 or 10
+//	EBYTE_RSSI, EBYTE_LBT             true or false
+//	EBYTE_FIXED                       true for fixed transmission
+//	EBYTE_WOR_MS                      WOR cycle in ms, 500-4000 in 500 ms steps
+//
+// Apply the module config with ConfigBuilder.FullConfig, and pass the pin config to common.NewHWHandler
+func ConfigFromEnv() (ModuleConfig, PinConfig, error) {
+	config := DefaultModuleConfig
+	pins := DefaultPinConfig
+	parsers := []struct {
+		name  string
+		parse func(string) error
+	}{
+		{"EBYTE_M0", envInt(&pins.M0)},
+		{"EBYTE_M1", envInt(&pins.M1)},
+		{"EBYTE_AUX", envInt(&pins.AUX)},
+		{"EBYTE_TTY", envString(&pins.TTY)},
+		{"EBYTE_GPIO_CHIP", envString(&pins.GPIOChip)},
+		{"EBYTE_ADDRESS", func(value string) error {
+			address, err := strconv.ParseUint(value, 0, 16)
+			if err != nil {
+				return err
+			}
+			config.Address.High, config.Address.Low = uint8(address>>8), uint8(address)
+			return nil
+		}},
+		{"EBYTE_CHANNEL", func(value string) error {
+			channel, err := strconv.ParseUint(value, 0, 8)
+			if err != nil {
+				return err
+			}
+			if channel > 80 {
+				return fmt.Errorf("channel must be in range 0-80")
+			}
+			config.RadioParams.Channel = uint8(channel)
+			return nil
+		}},
+		{"EBYTE_BAUD", func(value string) error {
+			v, err := envChoice(value, envBaudRates)
+			config.SerialParams.BaudRate = BaudRate(v)
+			return err
+		}},
+		{"EBYTE_PARITY", func(value string) error {
+			v, err := envChoice(value, envParities)
+			config.SerialParams.Parity = Parity(v)
+			return err
+		}},
+		{"EBYTE_AIR_RATE", func(value string) error {
+			v, err := envChoice(value, envAirDataRates)
+			config.RadioParams.AirDataRate = AirDataRate(v)
+			return err
+		}},
+		{"EBYTE_SUB_PACKET", func(value string) error {
+			v, err := envChoice(value, envSubPackets)
+			config.RadioParams.SubPacket = SubPacket(v)
+			return err
+		}},
+		{"EBYTE_POWER", func(value string) error {
+			v, err := envChoice(value, envPowers)
+			config.RadioParams.TransmittingPower = TransmittingPower(v)
+			return err
+		}},
+		{"EBYTE_RSSI", envBool(func(enabled bool) {
+			config.TransmissionParams.RSSI = RSSI_DISABLE
+			if enabled {
+				config.TransmissionParams.RSSI = RSSI_ENABLE
+			}
+		})},
+		{"EBYTE_LBT", envBool(func(enabled bool) {
+			config.TransmissionParams.LBT = LBT_DISABLE
+			if enabled {
+				config.TransmissionParams.LBT = LBT_ENABLE
+			}
+		})},
+		{"EBYTE_FIXED", envBool(func(fixed bool) {
+			config.TransmissionParams.Method = TRANSMISSION_TRANSPARENT
+			if fixed {
+				config.TransmissionParams.Method = TRANSMISSION_FIXED
+			}
+		})},
+		{"EBYTE_WOR_MS", func(value string) error {
+			v, err := envChoice(value, envWORCycles)
+			config.TransmissionParams.WORCycle = WORCycle(v)
+			return err
+		}},
+	}
+	for _, p := range parsers {
+		value, ok := os.LookupEnv(p.name)
+		if !ok {
+			continue
+		}
+		err := p.parse(value)
+		if err != nil {
+			return ModuleConfig{}, PinConfig{}, fmt.Errorf("invalid %s value %q: %w", p.name, value, err)
+		}
+	}
+	return config, pins, nil
+}
+
+// envInt returns parser that stores integer value to dst
+func envInt(dst *int) func(string) error {
+	return func(value string) error {
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		*dst = v
+		return nil
+	}
+}
+
+// envString returns parser that stores value to dst
+func envString(dst *string) func(string) error {
+	return func(value string) error {
+		*dst = value
+		return nil
+	}
+}
+
+// envBool returns parser that passes boolean value to set
+func envBool(set func(bool)) func(string) error {
+	return func(value string) error {
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		set(v)
+		return nil
+	}
+}
+
+// envChoice returns register value for the given env value
+func envChoice(value string, choices map[string]uint8) (uint8, error) {
+	v, ok := choices[value]
+	if !ok {
+		return 0, fmt.Errorf("unsupported value")
+	}
+	return v, nil
+}
+
+var envBaudRates = map[string]uint8{
+	"1200": uint8(BAUD_1200), "2400": uint8(BAUD_2400), "4800": uint8(BAUD_4800), "9600": uint8(BAUD_9600),
+	"19200": uint8(BAUD_19200), "38400": uint8(BAUD_38400), "57600": uint8(BAUD_57600), "115200": uint8(BAUD_115200),
+}
+
+var envParities = map[string]uint8{
+	"8N1": uint8(PARITY_8N1), "8O1": uint8(PARITY_8O1), "8E1": uint8(PARITY_8E1),
+}
+
+// ADR_2400_0 and ADR_2400_1 have the same rate as ADR_2400, the documented one is used
+var envAirDataRates = map[string]uint8{
+	"2400": uint8(ADR_2400), "4800": uint8(ADR_4800), "9600": uint8(ADR_9600),
+	"19200": uint8(ADR_19200), "38400": uint8(ADR_38400), "62500": uint8(ADR_62500),
+}
+
+var envSubPackets = map[string]uint8{
+	"200": uint8(BYTES_200), "128": uint8(BYTES_128), "64": uint8(BYTES_64), "32": uint8(BYTES_32),
+}
+
+var envPowers = map[string]uint8{
+	"22": uint8(TP_22_DBM), "17": uint8(TP_17_DBM), "13": uint8(TP_13_DBM), "10": uint8(TP_10_DBM),
+}
+
+var envWORCycles = map[string]uint8{
+	"500": uint8(WOR_500_MS), "1000": uint8(WOR_1000_MS), "1500": uint8(WOR_1500_MS), "2000": uint8(WOR_2000_MS),
+	"2500": uint8(WOR_2500_MS), "3000": uint8(WOR_3000_MS), "3500": uint8(WOR_3500_MS), "4000": uint8(WOR_4000_MS),
+}
@@ -0,0 +1,53 @@
+package e22
+
+import (
+	"errors"
+
+	"github.com/mbalug7/go-ebyte-lora/pkg/hal"
+)
+
+// ErrTargetBackoff is returned when a reliable send is skipped because the target is backing off after previous failures
+var ErrTargetBackoff = errors.New("target is backing off after previous send failures")
+
+// ErrUnexpectedCommand is returned when the chip response doesn't start with the expected command byte
+var ErrUnexpectedCommand = errors.New("unexpected command in chip response")
+
+// ErrObserverModule is returned when an operation would change the chip state of the module created with NewObserverModule
+var ErrObserverModule = errors.New("operation is not allowed on observer module")
+
+// ErrChipBusyTimeout is returned when the module doesn't release AUX line in time, it is the same error as
+// hal.ErrChipBusyTimeout. Busy timeouts are usually temporary, the operation can be retried
+var ErrChipBusyTimeout = hal.ErrChipBusyTimeout
+
+// ErrInvalidConfigResponse is returned when the chip response to a register or info command can't be parsed
+var ErrInvalidConfigResponse = errors.New("invalid chip response")
+
+// ErrModeNotTransmittable is returned when a send is requested while the chip is in a mode in which it can't transmit
+var ErrModeNotTransmittable = errors.New("chip mode doesn't allow transmission")
+
+// ErrTransmissionMethodMismatch is returned when the send method doesn't match the transmission method set on the chip
+var ErrTransmissionMethodMismatch = errors.New("send method doesn't match chip transmission method")
+
+// ErrConfigMismatch is returned when the config read back after a write differs from the written config
+var ErrConfigMismatch = errors.New("chip config differs from the written config")
+
+// ErrEmptyPayload is returned when a send is requested with an empty payload, see WithEmptyPayload
+var ErrEmptyPayload = errors.New("empty payload")
+
+// ErrUnsupportedFirmware is returned when the module firmware is older than the version that is required for
+// the operation, see WithMinFirmwareVersion
+var ErrUnsupportedFirmware = errors.New("operation is not supported by the module firmware")
+
+// transmitError wraps error of the write to the chip, it separates transmission failures from the errors of the
+// checks that are done before the write
+type transmitError struct {
+	err error
+}
+
+func (obj *transmitError) Error() string {
+	return obj.err.Error()
+}
+
+func (obj *transmitError) Unwrap() error {
+	return obj.err
+}
@@ -0,0 +1,35 @@
+package e22
+
+import "fmt"
+
+// Feature module operation that depends on the firmware revision, see WithMinFirmwareVersion
+type Feature string
+
+const (
+	// FeatureChannelRSSI RSSI read command (C0 C1 C2 C3), used by ReadChannelRSSI and ambient RSSI sampling
+	FeatureChannelRSSI Feature = "channel RSSI read"
+)
+
+// requireFirmware returns ErrUnsupportedFirmware if the module firmware is older than the version set for the feature
+// with WithMinFirmwareVersion. Product info is read from the chip on the first check and cached.
+// Caller must not hold muSend
+func (obj *Module) requireFirmware(feature Feature) error {
+	minVersion, ok := obj.minFirmware[feature]
+	if !ok {
+		return nil
+	}
+	obj.muProductInfo.Lock()
+	defer obj.muProductInfo.Unlock()
+	if obj.productInfo == nil {
+		info, err := obj.ReadProductInfo()
+		if err != nil {
+			return fmt.Errorf("failed to check firmware version for %s: %w", feature, err)
+		}
+		obj.productInfo = &info
+	}
+	if obj.productInfo.Version < minVersion {
+		return fmt.Errorf("%w: %s needs version 0x%02X, module has 0x%02X", ErrUnsupportedFirmware, feature, minVersion,
+			obj.productInfo.Version)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package e22
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestReadChannelRSSIRejectsOldFirmware(t *testing.T) {
+	module, hw := newTestModule(t, nil, WithMinFirmwareVersion(FeatureChannelRSSI, 0x20))
+	module.muRegisters.Lock()
+	module.registers[REG1].(*Reg1).ambientNoiseRSSI = RSSI_AMBIENT_NOISE_ENABLE
+	module.muRegisters.Unlock()
+	hw.reads = [][]byte{{0xC1, 0x00, 0x07, 0x22, 0x10, 0x01, 0x00}}
+
+	for i := 0; i < 2; i++ {
+		_, _, err := module.ReadChannelRSSI()
+		if !errors.Is(err, ErrUnsupportedFirmware) {
+			t.Fatalf("expected ErrUnsupportedFirmware, got: %v", err)
+		}
+	}
+	// product info is read once, and the RSSI read command is never sent
+	if hw.writeCount() != 1 {
+		t.Fatalf("%d writes, expected only the product info read", hw.writeCount())
+	}
+}
+
+func TestRequireFirmware(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []ModuleOption
+		version byte
+		expErr  error
+		writes  int
+	}{
+		{name: "no requirement", writes: 0},
+		{name: "newer firmware", opts: []ModuleOption{WithMinFirmwareVersion(FeatureChannelRSSI, 0x10)}, version: 0x12, writes: 1},
+		{name: "older firmware", opts: []ModuleOption{WithMinFirmwareVersion(FeatureChannelRSSI, 0x10)}, version: 0x0F,
+			expErr: ErrUnsupportedFirmware, writes: 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			module, hw := newTestModule(t, nil, test.opts...)
+			hw.reads = [][]byte{{0xC1, 0x00, 0x07, 0x22, test.version, 0x01, 0x00}}
+			err := module.requireFirmware(FeatureChannelRSSI)
+			if !errors.Is(err, test.expErr) {
+				t.Fatalf("expected %v, got: %v", test.expErr, err)
+			}
+			if hw.writeCount() != test.writes {
+				t.Fatalf("%d writes, expected %d", hw.writeCount(), test.writes)
+			}
+		})
+	}
+}
+
+func TestRequireFirmwareReadError(t *testing.T) {
+	module, hw := newTestModule(t, nil, WithMinFirmwareVersion(FeatureChannelRSSI, 0x10))
+	hw.reads = [][]byte{{0xC1, 0x00, 0x07}}
+	err := module.requireFirmware(FeatureChannelRSSI)
+	if !errors.Is(err, ErrInvalidConfigResponse) {
+		t.Fatalf("expected ErrInvalidConfigResponse, got: %v", err)
+	}
+	if module.productInfo != nil {
+		t.Fatal("product info is cached after a failed read")
+	}
+}
@@ -0,0 +1,62 @@
+package e22
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// maxFramerBufferSize max number of bytes that framer holds while waiting for the end of the frame
+const maxFramerBufferSize = 4096
+
+// framer reassembles application frames from the chunks that are received from the serial port
+type framer interface {
+	// push appends received chunk to the internal buffer and returns all completed frames
+	push(chunk []byte) ([][]byte, error)
+}
+
+// delimiterFramer splits received stream on the delimiter byte
+type delimiterFramer struct {
+	delimiter byte
+	buf       []byte
+}
+
+func (obj *delimiterFramer) push(chunk []byte) ([][]byte, error) {
+	obj.buf = append(obj.buf, chunk...)
+	var frames [][]byte
+	for {
+		i := bytes.IndexByte(obj.buf, obj.delimiter)
+		if i < 0 {
+			break
+		}
+		frame := make([]byte, i)
+		copy(frame, obj.buf[:i])
+		frames = append(frames, frame)
+		obj.buf = obj.buf[i+1:]
+	}
+	if len(obj.buf) > maxFramerBufferSize {
+		obj.buf = nil
+		return frames, fmt.Errorf("frame delimiter not received in %d bytes, dropping buffered data", maxFramerBufferSize)
+	}
+	return frames, nil
+}
+
+// lengthPrefixFramer splits received stream into frames, where the first byte of each frame defines payload length
+type lengthPrefixFramer struct {
+	buf []byte
+}
+
+func (obj *lengthPrefixFramer) push(chunk []byte) ([][]byte, error) {
+	obj.buf = append(obj.buf, chunk...)
+	var frames [][]byte
+	for len(obj.buf) > 0 {
+		length := int(obj.buf[0])
+		if len(obj.buf) < length+1 {
+			break
+		}
+		frame := make([]byte, length)
+		copy(frame, obj.buf[1:length+1])
+		frames = append(frames, frame)
+		obj.buf = obj.buf[length+1:]
+	}
+	return frames, nil
+}
@@ -0,0 +1,97 @@
+package e22
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFramerReassemblesChunks(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []ModuleOption
+		chunks [][]byte
+		frames []string
+		errs   int
+	}{
+		{
+			name:   "delimiter, split frame",
+			opts:   []ModuleOption{WithLineDelimiter('\n')},
+			chunks: [][]byte{[]byte("AST"), []byte("AT"), []byte("US\n")},
+			frames: []string{"ASTATUS"},
+		},
+		{
+			name:   "delimiter, concatenated frames",
+			opts:   []ModuleOption{WithLineDelimiter('\n')},
+			chunks: [][]byte{[]byte("one\ntwo\nthr"), []byte("ee\n")},
+			frames: []string{"one", "two", "three"},
+		},
+		{
+			name:   "delimiter, garbage between frames",
+			opts:   []ModuleOption{WithLineDelimiter('\n')},
+			chunks: [][]byte{[]byte("one\n\xFF\x00\n"), []byte("two\n")},
+			frames: []string{"one", "\xFF\x00", "two"},
+		},
+		{
+			name:   "delimiter, garbage without delimiter",
+			opts:   []ModuleOption{WithLineDelimiter('\n')},
+			chunks: [][]byte{bytes.Repeat([]byte{0xFF}, maxFramerBufferSize), {0xFF}, []byte("one\n")},
+			frames: []string{"one"},
+			errs:   1,
+		},
+		{
+			name:   "delimiter, RSSI byte in every chunk",
+			opts:   []ModuleOption{WithLineDelimiter('\n'), WithPeerRSSI(true)},
+			chunks: [][]byte{[]byte("on\xA0"), []byte("e\ntwo\n\xA1")},
+			frames: []string{"one", "two"},
+		},
+		{
+			name:   "length prefix, split frame",
+			opts:   []ModuleOption{WithLengthPrefix()},
+			chunks: [][]byte{{0x07}, []byte("ASTA"), []byte("TUS")},
+			frames: []string{"ASTATUS"},
+		},
+		{
+			name:   "length prefix, concatenated frames",
+			opts:   []ModuleOption{WithLengthPrefix()},
+			chunks: [][]byte{[]byte("\x03one\x03two\x05th"), []byte("ree")},
+			frames: []string{"one", "two", "three"},
+		},
+		{
+			name:   "length prefix, padding between frames",
+			opts:   []ModuleOption{WithLengthPrefix()},
+			chunks: [][]byte{[]byte("\x03one\x00"), []byte("\x03two")},
+			frames: []string{"one", "", "two"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var frames []string
+			errs := 0
+			module, hw := newTestModule(t, func(msg Message, err error) {
+				if err != nil {
+					errs++
+					return
+				}
+				frames = append(frames, string(msg.Payload))
+			}, test.opts...)
+			err := hw.RegisterOnMessageCb(module.onMessageHandler)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, chunk := range test.chunks {
+				hw.onMsg(chunk, nil)
+			}
+			if len(frames) != len(test.frames) {
+				t.Fatalf("received frames %q, expected %q", frames, test.frames)
+			}
+			for i := range frames {
+				if frames[i] != test.frames[i] {
+					t.Fatalf("received frames %q, expected %q", frames, test.frames)
+				}
+			}
+			if errs != test.errs {
+				t.Fatalf("%d errors, expected %d", errs, test.errs)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package e22
+
+// FullConfig typed representation of all readable module registers, grouped by purpose
+type FullConfig struct {
+	Address            AddressParams      `json:"address"`
+	SerialParams       SerialParams       `json:"serial"`
+	RadioParams        RadioParams        `json:"radio"`
+	TransmissionParams TransmissionParams `json:"transmission"`
+}
+
+// AddressParams ADD_H and ADD_L registers
+type AddressParams struct {
+	High uint8 `json:"high"`
+	Low  uint8 `json:"low"`
+}
+
+// SerialParams serial port parameters stored in REG0
+type SerialParams struct {
+	BaudRate BaudRate `json:"baud_rate"`
+	Parity   Parity   `json:"parity"`
+}
+
+// RadioParams air data rate from REG0, REG1 parameters and channel from REG2
+type RadioParams struct {
+	AirDataRate       AirDataRate       `json:"air_data_rate"`
+	SubPacket         SubPacket         `json:"sub_packet"`
+	AmbientNoiseRSSI  RSSIAmbientNoise  `json:"ambient_noise_rssi"`
+	TransmittingPower TransmittingPower `json:"transmitting_power"`
+	Channel           uint8             `json:"channel"`
+}
+
+// TransmissionParams REG3 parameters
+type TransmissionParams struct {
+	RSSI     EnableRSSI         `json:"rssi"`
+	Method   TransmissionMethod `json:"method"`
+	LBT      LBT                `json:"lbt"`
+	WORCycle WORCycle           `json:"wor_cycle"`
+}
+
+// newFullConfig constructs FullConfig from the given register collection
+func newFullConfig(registers registersCollection) FullConfig {
+	reg0 := registers[REG0].(*Reg0)
+	reg1 := registers[REG1].(*Reg1)
+	reg3 := registers[REG3].(*Reg3)
+	return FullConfig{
+		Address: AddressParams{
+			High: registers[ADD_H].GetValue(),
+			Low:  registers[ADD_L].GetValue(),
+		},
+		SerialParams: SerialParams{
+			BaudRate: reg0.baudRate,
+			Parity:   reg0.parityBit,
+		},
+		RadioParams: RadioParams{
+			AirDataRate:       reg0.adRate,
+			SubPacket:         reg1.subPacket,
+			AmbientNoiseRSSI:  reg1.ambientNoiseRSSI,
+			TransmittingPower: reg1.transmittingPower,
+			Channel:           registers[REG2].GetValue(),
+		},
+		TransmissionParams: TransmissionParams{
+			RSSI:     reg3.enableRSSI,
+			Method:   reg3.transmissionMethod,
+			LBT:      reg3.lbtEnable,
+			WORCycle: reg3.worCycle,
+		},
+	}
+}
+
+// stage sets config values to the given register collection
+func (obj FullConfig) stage(registers registersCollection) {
+	registers[ADD_H].SetValue(obj.Address.High)
+	registers[ADD_L].SetValue(obj.Address.Low)
+
+	reg0 := registers[REG0].(*Reg0)
+	reg0.baudRate = obj.SerialParams.BaudRate
+	reg0.parityBit = obj.SerialParams.Parity
+	reg0.adRate = obj.RadioParams.AirDataRate
+
+	reg1 := registers[REG1].(*Reg1)
+	reg1.subPacket = obj.RadioParams.SubPacket
+	reg1.ambientNoiseRSSI = obj.RadioParams.AmbientNoiseRSSI
+	reg1.transmittingPower = obj.RadioParams.TransmittingPower
+
+	registers[REG2].SetValue(obj.RadioParams.Channel)
+
+	reg3 := registers[REG3].(*Reg3)
+	reg3.enableRSSI = obj.TransmissionParams.RSSI
+	reg3.transmissionMethod = obj.TransmissionParams.Method
+	reg3.lbtEnable = obj.TransmissionParams.LBT
+	reg3.worCycle = obj.TransmissionParams.WORCycle
+}
+
+// ReadAllConfig reads all readable registers from the chip and returns them as FullConfig
+func (obj *Module) ReadAllConfig() (FullConfig, error) {
+	err := obj.ReadConfigFromChip()
+	if err != nil {
+		return FullConfig{}, err
+	}
+	return newFullConfig(obj.currentRegisters()), nil
+}
+
+// WriteAllConfig permanently writes the given config to the chip, nothing is written if the chip already has the same
+// config, see LastWriteChanged. Config is checked with ConfigBuilder.Validate first, invalid config is not written.
+// Crypt registers are not part of FullConfig, use ConfigBuilder.Crypt to set the encryption key
+func (obj *Module) WriteAllConfig(config FullConfig) error {
+	_, err := NewConfigBuilder(obj).FullConfig(config).writeValidated(false)
+	return err
+}
@@ -0,0 +1,54 @@
+package e22
+
+import (
+	"testing"
+)
+
+func TestWriteAllConfigReportsChange(t *testing.T) {
+	module, hw := newTestModule(t, nil)
+	config := newFullConfig(module.currentRegisters())
+
+	err := module.WriteAllConfig(config)
+	if err != nil {
+		t.Fatalf("failed to write unchanged config: %v", err)
+	}
+	if module.LastWriteChanged() {
+		t.Fatal("unchanged config is reported as changed")
+	}
+	if hw.writeCount() != 0 {
+		t.Fatalf("%d writes of the unchanged config, expected none", hw.writeCount())
+	}
+
+	config.RadioParams.Channel = 0x12
+	hw.reads = [][]byte{{DefaultCommandSet.GetReg, REG2.ToByte(), 0x01, 0x12}}
+	err = module.WriteAllConfig(config)
+	if err != nil {
+		t.Fatalf("failed to write changed config: %v", err)
+	}
+	if !module.LastWriteChanged() {
+		t.Fatal("changed config is reported as unchanged")
+	}
+}
+
+func TestWriteAllConfigRejectsInvalidConfig(t *testing.T) {
+	tests := map[string]func(*FullConfig){
+		"channel":            func(c *FullConfig) { c.RadioParams.Channel = 0x60 },
+		"transmitting power": func(c *FullConfig) { c.RadioParams.TransmittingPower = 0x04 },
+		"baud rate":          func(c *FullConfig) { c.SerialParams.BaudRate = 0x01 },
+		"WOR cycle":          func(c *FullConfig) { c.TransmissionParams.WORCycle = 0x08 },
+	}
+	for name, corrupt := range tests {
+		t.Run(name, func(t *testing.T) {
+			module, hw := newTestModule(t, nil)
+			config := newFullConfig(module.currentRegisters())
+			corrupt(&config)
+			err := module.WriteAllConfig(config)
+			if err == nil {
+				t.Fatal("invalid config is written")
+			}
+			if hw.writeCount() != 0 {
+				t.Fatalf("%d writes of an invalid config, expected none", hw.writeCount())
+			}
+		})
+	}
+}
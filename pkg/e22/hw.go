@@ -0,0 +1,73 @@
+package e22
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mbalug7/go-ebyte-lora/pkg/hal"
+)
+
+// writeSerialContext writes msg with the handler WriteSerialContext, or with WriteSerial if ctx is not done yet
+func (obj *Module) writeSerialContext(ctx context.Context, msg []byte) error {
+	if handler, ok := obj.hw.(hal.ContextHandler); ok {
+		return handler.WriteSerialContext(ctx, msg)
+	}
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+	return obj.hw.WriteSerial(msg)
+}
+
+// setModeContext sets chip mode with the handler SetModeContext, or with SetMode if ctx is not done yet
+func (obj *Module) setModeContext(ctx context.Context, mode hal.ChipMode) error {
+	if handler, ok := obj.hw.(hal.ContextHandler); ok {
+		return handler.SetModeContext(ctx, mode)
+	}
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+	return obj.hw.SetMode(mode)
+}
+
+// writeSerialBurst writes frames with the handler WriteSerialBurst, or one by one with WriteSerial
+func (obj *Module) writeSerialBurst(frames [][]byte) error {
+	if handler, ok := obj.hw.(hal.BurstWriter); ok {
+		return handler.WriteSerialBurst(frames)
+	}
+	for _, frame := range frames {
+		err := obj.hw.WriteSerial(frame)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flushSerial drops unread received data if the handler supports it
+func (obj *Module) flushSerial() error {
+	if handler, ok := obj.hw.(hal.SerialFlusher); ok {
+		return handler.FlushSerial()
+	}
+	return nil
+}
+
+// setConfigModeBaud sets ModeSleep baud rate, returns hal.ErrNotSupported if the handler can't change it
+func (obj *Module) setConfigModeBaud(baudRate int) error {
+	handler, ok := obj.hw.(hal.ConfigBaudSetter)
+	if !ok {
+		return fmt.Errorf("%w: config mode baud rate can't be changed", hal.ErrNotSupported)
+	}
+	return handler.SetConfigModeBaud(baudRate)
+}
+
+// waitAUXIdle waits until the module is idle, returns hal.ErrNotSupported if the handler can't wait for AUX
+func (obj *Module) waitAUXIdle(timeout time.Duration) error {
+	handler, ok := obj.hw.(hal.AUXIdleWaiter)
+	if !ok {
+		return fmt.Errorf("%w: AUX line state is not available", hal.ErrNotSupported)
+	}
+	return handler.WaitAUXIdle(timeout)
+}
@@ -0,0 +1,62 @@
+package e22
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mbalug7/go-ebyte-lora/pkg/hal"
+	"github.com/tarm/serial"
+)
+
+// coreHW handler that implements only hal.HWHandler, without any optional interface
+type coreHW struct {
+	hw *fakeHW
+}
+
+func (obj *coreHW) ReadSerial() ([]byte, error) {
+	return obj.hw.ReadSerial()
+}
+
+func (obj *coreHW) WriteSerial(msg []byte) error {
+	return obj.hw.WriteSerial(msg)
+}
+
+func (obj *coreHW) StageSerialPortConfig(baudRate int, parityBit serial.Parity) {
+}
+
+func (obj *coreHW) SetMode(mode hal.ChipMode) error {
+	return obj.hw.SetMode(mode)
+}
+
+func (obj *coreHW) GetMode() (hal.ChipMode, error) {
+	return obj.hw.GetMode()
+}
+
+func (obj *coreHW) RegisterOnMessageCb(cb hal.OnMessageCb) error {
+	return obj.hw.RegisterOnMessageCb(cb)
+}
+
+func TestModuleWithCoreHandler(t *testing.T) {
+	fake := &fakeHW{mode: hal.ModeNormal}
+	fake.reads = [][]byte{{DefaultCommandSet.GetReg, ADD_H.ToByte(), 0x06, 0x00, 0x03, 0x62, 0x00, 0x12, 0x00}}
+	module, err := NewModule(&coreHW{hw: fake}, nil)
+	if err != nil {
+		t.Fatalf("failed to construct module on the core handler: %v", err)
+	}
+	defer module.Close()
+	if module.Channel() != 0x12 {
+		t.Fatalf("channel is 0x%02X, expected 0x12", module.Channel())
+	}
+
+	err = module.SendBurst([][]byte{[]byte("one"), []byte("two")})
+	if err != nil {
+		t.Fatalf("burst failed without BurstWriter: %v", err)
+	}
+	if fake.writeCount() != 3 {
+		t.Fatalf("%d writes, expected the config read and one write per frame", fake.writeCount())
+	}
+	if err := module.WaitTxComplete(time.Second); !errors.Is(err, hal.ErrNotSupported) {
+		t.Fatalf("expected hal.ErrNotSupported without AUXIdleWaiter, got: %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package e22
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+)
+
+// Target fixed transmission destination
+type Target struct {
+	AddressHigh byte
+	AddressLow  byte
+	Channel     byte
+}
+
+// linkProbePrefix marks link test probe payloads
+var linkProbePrefix = []byte("LNKT")
+
+// LinkReport summary of the link test
+type LinkReport struct {
+	Sent        int
+	Received    int
+	SuccessRate float64         // received / sent
+	RSSI        []uint8         // RSSI of every received reply, empty if RSSI is not enabled on the module
+	RTT         []time.Duration // round trip time of every received reply
+	MinRTT      time.Duration   // zero if nothing is received
+	MaxRTT      time.Duration   // zero if nothing is received
+	AvgRTT      time.Duration   // zero if nothing is received
+}
+
+// LinkTest sends count probe frames to the target, one every interval, and waits for the target to echo each probe.
+// The target must send every received probe payload back unchanged, to the address and channel of this module.
+// Replies are consumed by the link test and are not passed to the message callback.
+// Module must be in TRANSMISSION_FIXED mode. Partial report is returned if the context is canceled
+func (obj *Module) LinkTest(ctx context.Context, target Target, count int, interval time.Duration) (report LinkReport, err error) {
+	defer report.summarize()
+	for i := 0; i < count; i++ {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		probe := append(append([]byte{}, linkProbePrefix...), byte(i>>8), byte(i))
+		waiter := obj.addRxWaiter(func(msg Message) bool {
+			return bytes.Equal(msg.Payload, probe)
+		})
+		sentAt := obj.clock.Now()
+		err = obj.SendFixedBytes(target.AddressHigh, target.AddressLow, target.Channel, probe)
+		if err != nil {
+			obj.removeRxWaiter(waiter)
+			return report, fmt.Errorf("failed to send link test probe %d: %w", i, err)
+		}
+		report.Sent++
+
+		next := obj.clock.After(interval)
+		select {
+		case <-ctx.Done():
+			obj.removeRxWaiter(waiter)
+			return report, ctx.Err()
+		case <-next:
+			obj.removeRxWaiter(waiter)
+			continue
+		case msg := <-waiter.ch:
+			report.Received++
+			report.RTT = append(report.RTT, obj.clock.Now().Sub(sentAt))
+			if obj.rssiAppended() {
+				report.RSSI = append(report.RSSI, msg.RSSI)
+			}
+		}
+		// keep probes evenly spaced
+		select {
+		case <-ctx.Done():
+			return report, ctx.Err()
+		case <-next:
+		}
+	}
+	return report, nil
+}
+
+// summarize calculates success rate and round trip time statistics
+func (obj *LinkReport) summarize() {
+	if obj.Sent > 0 {
+		obj.SuccessRate = float64(obj.Received) / float64(obj.Sent)
+	}
+	if len(obj.RTT) == 0 {
+		return
+	}
+	var total time.Duration
+	obj.MinRTT = obj.RTT[0]
+	for _, rtt := range obj.RTT {
+		total += rtt
+		if rtt < obj.MinRTT {
+			obj.MinRTT = rtt
+		}
+		if rtt > obj.MaxRTT {
+			obj.MaxRTT = rtt
+		}
+	}
+	obj.AvgRTT = total / time.Duration(len(obj.RTT))
+}
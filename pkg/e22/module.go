@@ -1,9 +1,12 @@
 package e22
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/mbalug7/go-ebyte-lora/pkg/hal"
@@ -13,7 +16,17 @@ import (
 // Message struct that holds received data
 type Message struct {
 	Payload []byte
-	RSSI    uint8
+	RSSI    uint8 // raw RSSI byte appended by the module, use RSSIdBm to get the signal strength
+}
+
+// RSSIdBm returns signal strength of the received message in dBm, -(256 - RSSI) as the datasheet documents it
+func (obj Message) RSSIdBm() int {
+	return rssiToDBm(obj.RSSI)
+}
+
+// rssiToDBm converts raw RSSI byte to dBm
+func rssiToDBm(raw uint8) int {
+	return -(256 - int(raw))
 }
 
 // OnMessageCb defines on message callback type
@@ -23,8 +36,25 @@ const (
 	cmdSetRegPermanent byte = 0xC0
 	cmdGetReg          byte = 0xC1
 	cmdSetRegTemporary byte = 0xC2
+	cmdReset           byte = 0xC4
 )
 
+// CommandSet register command bytes that are sent to the chip
+type CommandSet struct {
+	SetRegPermanent byte
+	GetReg          byte // also the first byte of every register command response
+	SetRegTemporary byte
+	Reset           byte
+}
+
+// DefaultCommandSet command bytes of the EBYTE firmware
+var DefaultCommandSet = CommandSet{
+	SetRegPermanent: cmdSetRegPermanent,
+	GetReg:          cmdGetReg,
+	SetRegTemporary: cmdSetRegTemporary,
+	Reset:           cmdReset,
+}
+
 // chipRsp defines module response structure
 type chipRsp struct {
 	command   byte
@@ -33,7 +63,7 @@ type chipRsp struct {
 	params    []byte
 }
 
-var serialBaudMap = map[baudRate]int{
+var serialBaudMap = map[BaudRate]int{
 	BAUD_1200:   1200,
 	BAUD_2400:   2400,
 	BAUD_4800:   4800,
@@ -44,100 +74,429 @@ var serialBaudMap = map[baudRate]int{
 	BAUD_115200: 115200,
 }
 
-var serialParityMap = map[parity]serial.Parity{
+var serialParityMap = map[Parity]serial.Parity{
 	PARITY_8N1: serial.ParityNone,
 	PARITY_8O1: serial.ParityOdd,
 	PARITY_8E1: serial.ParityEven,
 }
 
+var subPacketSizeMap = map[SubPacket]int{
+	BYTES_200: 200,
+	BYTES_128: 128,
+	BYTES_64:  64,
+	BYTES_32:  32,
+}
+
 // Module E22 module object
 type Module struct {
-	registers registersCollection
+	registers   registersCollection // registers model, use currentRegisters to read it
+	muRegisters sync.RWMutex
+	// true if the last config write was temporary, protected with muRegisters
+	volatileConfig bool
+	// true if the last WriteConfigToChip call changed the chip config, protected with muRegisters
+	lastWriteChanged bool
+	// true if a crypt key was written in this process, protected with muRegisters
+	cryptKeySet bool
+
 	hw        hal.HWHandler
 	onMsgCb   OnMessageCb
+	framer    framer // optional, reassembles received data into application frames
+	backoff   map[fixedTarget]*backoffState
+	muBackoff sync.Mutex
+	onWake    func(time.Time) // optional, called when the module wakes up in ModePowerSave
+	onSleep   func(time.Time) // optional, called when the module goes back to sleep in ModePowerSave
+	stopBits  serial.StopBits // serial stop bits, E22 uses 1 stop bit
+	muSend    sync.Mutex      // sends and config mode operations consist of multiple steps, they must not interleave
+
+	idempotentWrites bool // ConfigBuilder writes of the config that is already on the chip are successful
+
+	payloadSizes map[int]int // optional, received payload length histogram
+	lastRx       time.Time   // time of the last received message
+	created      time.Time   // module construction time
+	muStats      sync.Mutex
+
+	clock hal.Clock // source of time for timeouts and delays
+
+	resetThreshold int         // number of consecutive busy timeouts after which the chip is reset, 0 disables it
+	onReset        func(error) // optional, called after automatic reset with the reset result
+	busyTimeouts   int         // number of consecutive busy timeouts, protected with muSend
+
+	maxPayloadSize    int  // max payload length that firmware transmits in one frame, set only by WithMaxPayloadSize, 0 if unknown
+	allowEmptyPayload bool // empty payloads are sent instead of rejected with ErrEmptyPayload
+
+	baseFrequency float64 // frequency of the channel 0 in MHz
+
+	variant  Variant    // chip register layout
+	commands CommandSet // register command bytes
+
+	initTimeout   time.Duration // time during which the initial config read is retried, single attempt if 0
+	baudDetection bool          // config mode baud rate is detected if the initial config read fails
+
+	peerRSSI *bool // optional, overrides RSSI setting of the chip when received data is parsed
+
+	recorder *frameRecorder // optional, captures received data for ReplayFrames
+
+	rxQueueSize int            // capacity of the receive queue, 0 disables the queue
+	rxWorkers   int            // number of goroutines that call the message callback from the receive queue
+	rxPolicy    OverflowPolicy // what to do with a received message when the queue is full
+	rxQueue     chan rxItem
+	rxQueueStop chan struct{} // closed on Close, stops the receive queue workers
+	rxStopOnce  sync.Once
+	rxQueueDone chan struct{} // closed when the receive queue workers exit
+	rxCallbacks int32         // number of callbacks that are running on the receive queue workers
+	rxOverflows uint64        // number of messages dropped because of the full queue, protected with muStats
+
+	observer bool // module never changes chip mode nor writes anything to the chip
+
+	rxAddressFilter bool   // received frames carry destination address header, frames for other nodes are dropped
+	rxAddress       uint16 // address of this node, used by rxAddressFilter
+
+	rxWaiters   []*rxWaiter // waiters that consume matching received messages
+	muRxWaiters sync.Mutex
+	muTransact  sync.Mutex // Transact calls must not overlap
+
+	minFirmware   map[Feature]byte // min firmware version per feature, set by WithMinFirmwareVersion
+	productInfo   *ProductInfo     // cached product info for the firmware checks, protected with muProductInfo
+	muProductInfo sync.Mutex
+
+	rssiReply   chan []byte // set while ReadChannelRSSI waits for the chip response
+	muRSSIReply sync.Mutex
+
+	ambientInterval time.Duration // ambient RSSI sampling interval, 0 disables sampling
+	ambientSamples  []uint8       // ring buffer of ambient RSSI samples, protected with muStats
+	ambientNext     int           // ring buffer index of the next sample, protected with muStats
+	ambientFull     bool          // ring buffer is wrapped, protected with muStats
+	stopAmbient     chan struct{} // closed on Close, stops the sampler
+	ambientDone     chan struct{} // closed when the sampler exits
+
+	echoWindow time.Duration // received data equal to the last written data within this window is dropped, 0 disables it
+	lastTx     []byte        // last data written to the chip, protected with muStats
+	lastTxTime time.Time     // time of the last write, protected with muStats
+	echoes     uint64        // number of dropped echoes, protected with muStats
 }
 
 // NewModule constract new E22 module, reads current configuration and sets chip mode
-func NewModule(gpioHandler hal.HWHandler, cb OnMessageCb) (*Module, error) {
-	mode, err := gpioHandler.GetMode()
+func NewModule(gpioHandler hal.HWHandler, cb OnMessageCb, opts ...ModuleOption) (*Module, error) {
+	return NewModuleContext(context.Background(), gpioHandler, cb, opts...)
+}
+
+// NewModuleContext constructs module like NewModule, construction is aborted when ctx is done.
+// Mode switches and command writes stop waiting for the module when ctx is done, a response read that is in
+// progress is finished (or times out) before the construction returns ctx error
+func NewModuleContext(ctx context.Context, gpioHandler hal.HWHandler, cb OnMessageCb, opts ...ModuleOption) (*Module, error) {
+	ch := newModule(gpioHandler, cb, opts)
+	err := gpioHandler.RegisterOnMessageCb(ch.onMessageHandler)
+	if err != nil {
+		ch.Close()
+		return nil, fmt.Errorf("failed to register OnMessageCb: %w", err)
+	}
+	if notifier, ok := gpioHandler.(hal.AuxEdgeNotifier); ok {
+		err = notifier.RegisterOnAuxEdgeCb(ch.onAuxEdgeHandler)
+		if err != nil {
+			ch.Close()
+			return nil, fmt.Errorf("failed to register OnAuxEdgeCb: %w", err)
+		}
+	}
+	err = ch.initConfig(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get chip mode: %w", err)
+		ch.Close()
+		return nil, err
+	}
+	ch.startAmbientSampler()
+	return ch, nil
+}
+
+// initRetryInterval pause between the initial config read attempts
+const initRetryInterval = 500 * time.Millisecond
+
+// initConfig reads the config from the chip on construction and restores the chip mode. The read is retried until
+// initTimeout expires, if it still fails and baud detection is enabled, config mode baud rate is detected once
+func (obj *Module) initConfig(ctx context.Context) error {
+	mode, err := obj.hw.GetMode()
+	if err != nil {
+		return fmt.Errorf("failed to get chip mode: %w", err)
+	}
+	err = obj.readInitialConfig(ctx)
+	if err != nil && obj.baudDetection && ctx.Err() == nil {
+		detectErr := obj.detectConfigBaud(ctx)
+		if detectErr != nil {
+			err = fmt.Errorf("%w, %v", err, detectErr)
+		} else {
+			err = nil
+		}
 	}
+	if err != nil {
+		if ctx.Err() != nil && !errors.Is(err, ctx.Err()) {
+			return fmt.Errorf("%w, last config read error: %v", ctx.Err(), err)
+		}
+		return err
+	}
+	return obj.applyConfig(ctx, mode)
+}
+
+// readInitialConfig reads the config from the chip, the read is retried until initTimeout expires
+func (obj *Module) readInitialConfig(ctx context.Context) error {
+	deadline := obj.clock.Now().Add(obj.initTimeout)
+	for {
+		err := obj.readConfig(ctx)
+		if err == nil || ctx.Err() != nil || !obj.clock.Now().Before(deadline) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-obj.clock.After(initRetryInterval):
+		}
+	}
+}
+
+// newModule constructs module with default values, applies options and starts receive workers
+func newModule(gpioHandler hal.HWHandler, cb OnMessageCb, opts []ModuleOption) *Module {
 	ch := &Module{
 		hw:        gpioHandler,
 		registers: newRegistersCollection(),
 		onMsgCb:   cb,
+		backoff:   make(map[fixedTarget]*backoffState),
+		stopBits:  serial.Stop1,
+		variant:   VariantE22,
+		commands:  DefaultCommandSet,
+		clock:     hal.RealClock{},
+
+		baseFrequency: DefaultBaseFrequencyMHz,
+	}
+	for _, opt := range opts {
+		opt(ch)
 	}
-	err = gpioHandler.RegisterOnMessageCb(ch.onMessageHandler)
+	ch.created = ch.clock.Now()
+	ch.startRxQueue()
+	return ch
+}
+
+// Close stops receive workers, messages that are already queued are delivered before Close returns, unless a callback
+// is running on a receive worker, then Close returns without waiting. Hardware handler is not closed, it is owned by the caller
+func (obj *Module) Close() {
+	obj.stopAmbientSampler()
+	obj.stopRxQueue()
+}
+
+// configBaudCandidates baud rates that are tried when the module doesn't respond on the default config baud rate
+var configBaudCandidates = []int{9600, 115200, 57600, 38400, 19200, 4800, 2400, 1200}
+
+// reloadConfig reads current configuration from the chip, synchronizes it with the local registers model
+// and restores the chip mode that was set before reading
+func (obj *Module) reloadConfig(ctx context.Context) error {
+	mode, err := obj.hw.GetMode()
 	if err != nil {
-		return nil, fmt.Errorf("failed to register OnMessageCb: %w", err)
+		return fmt.Errorf("failed to get chip mode: %w", err)
 	}
-	// E22 module, first six registers are readable
-	data, err := ch.readChipRegisters(0x00, 0x06)
+	err = obj.readConfig(ctx)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	err = ch.saveConfig(data)
+	return obj.applyConfig(ctx, mode)
+}
+
+// applyConfig applies serial params of the config that is read from the chip to the serial port, and sets the chip
+// to the given mode
+func (obj *Module) applyConfig(ctx context.Context, mode hal.ChipMode) error {
+	err := ctx.Err()
 	if err != nil {
-		return nil, err
+		return err
+	}
+	err = obj.updateSerialStreamConfig()
+	if err != nil {
+		return fmt.Errorf("failed to update serial port config with the baud and parity values that are stored on chip: %w", err)
 	}
-	err = ch.updateSerialStreamConfig()
+	err = obj.setModeContext(ctx, mode)
 	if err != nil {
-		return nil, fmt.Errorf("failed to update serial port config with the baud and parity values that are stored on chip: %w", err)
+		return fmt.Errorf("failed to set chip mode: %w", err)
+	}
+	return nil
+}
+
+// ReadConfigFromChip reads the config from the chip again and applies its serial params to the serial port.
+// Use it when the config could be changed by someone else, e.g. after reset. Chip is switched to ModeSleep for
+// the read, and the previous mode is restored
+func (obj *Module) ReadConfigFromChip() error {
+	obj.muSend.Lock()
+	defer obj.muSend.Unlock()
+	return obj.readConfigFromChip()
+}
+
+// readConfigFromChip reads the config from the chip like ReadConfigFromChip, caller must hold muSend
+func (obj *Module) readConfigFromChip() error {
+	err := obj.reloadConfig(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to read config from the chip: %w", err)
+	}
+	return nil
+}
+
+// readConfig reads readable registers from the chip and saves them to lib model
+func (obj *Module) readConfig(ctx context.Context) error {
+	data, err := obj.readChipRegisters(ctx, obj.variant.ReadableStart, obj.variant.ReadableLength)
+	if err != nil {
+		return err
+	}
+	return obj.saveConfig(data)
+}
+
+// detectConfigBaud reads config with every supported baud rate, and keeps the first baud rate on which the module
+// responds with a valid config
+func (obj *Module) detectConfigBaud(ctx context.Context) error {
+	for _, baud := range configBaudCandidates {
+		if ctx.Err() != nil {
+			break
+		}
+		err := obj.setConfigModeBaud(baud)
+		if err != nil {
+			return fmt.Errorf("failed to set config mode baud rate %d: %w", baud, err)
+		}
+		if obj.readConfig(ctx) == nil {
+			return nil
+		}
 	}
-	err = ch.hw.SetMode(mode)
+	err := obj.setConfigModeBaud(configBaudCandidates[0])
 	if err != nil {
-		return nil, fmt.Errorf("failed to set chip mode: %w", err)
+		return fmt.Errorf("failed to reset config mode baud rate: %w", err)
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("baud rate detection aborted: %w", ctx.Err())
 	}
-	return ch, err
+	return fmt.Errorf("module didn't respond with a valid config on any supported baud rate")
 }
 
 // onMessageHandler parses received message and construct human readable message
+// if framer is defined, one message is emitted per reassembled application frame
 func (obj *Module) onMessageHandler(msg []byte, err error) {
 	if err != nil {
 		if errors.Is(err, io.EOF) {
 			return
 		}
-		obj.onMsgCb(Message{}, err)
+		obj.dispatch(Message{}, err)
 		return
 	}
-	if obj.registers[REG3].(*Reg3).enableRSSI == RSSI_ENABLE {
+	if obj.recorder != nil {
+		obj.recorder.record(msg, obj.clock.Now())
+	}
+	if obj.isEcho(msg) || obj.offerRSSIReply(msg) {
+		return
+	}
+	payload := msg
+	var rssi uint8
+	if obj.rssiAppended() {
 		if len(msg) < 2 {
-			obj.onMsgCb(Message{}, fmt.Errorf("invalid message received"))
+			obj.dispatch(Message{}, fmt.Errorf("invalid message received"))
+			return
+		}
+		payload = msg[0 : len(msg)-1]
+		rssi = msg[len(msg)-1]
+	}
+	if obj.framer == nil {
+		obj.deliver(Message{Payload: payload, RSSI: rssi})
+		return
+	}
+	frames, err := obj.framer.push(payload)
+	for _, frame := range frames {
+		obj.deliver(Message{Payload: frame, RSSI: rssi})
+	}
+	if err != nil {
+		obj.dispatch(Message{}, err)
+	}
+}
+
+// rssiAppended returns true if the last byte of the received data is RSSI. It is decided by the RSSI setting
+// on the chip, unless it is overridden with WithPeerRSSI
+func (obj *Module) rssiAppended() bool {
+	if obj.peerRSSI != nil {
+		return *obj.peerRSSI
+	}
+	return obj.currentRegisters()[REG3].(*Reg3).enableRSSI == RSSI_ENABLE
+}
+
+// deliver passes received message to the waiter that expects it, or to the message callback
+func (obj *Module) deliver(msg Message) {
+	if obj.rxAddressFilter {
+		payload, ok := obj.filterAddress(msg.Payload)
+		if !ok {
 			return
 		}
-		obj.onMsgCb(
-			Message{
-				Payload: msg[0 : len(msg)-1],
-				RSSI:    msg[len(msg)-1],
-			},
-			err,
-		)
+		msg.Payload = payload
+	}
+	obj.recordRx(len(msg.Payload))
+	if obj.offerToRxWaiter(msg) {
+		return
+	}
+	obj.dispatch(msg, nil)
+}
+
+// onAuxEdgeHandler reports WOR wake and sleep events. In ModePowerSave the module wakes up on preamble,
+// holds AUX low while received data is output to UART, and releases it when it goes back to sleep
+func (obj *Module) onAuxEdgeHandler(rising bool, t time.Time) {
+	if obj.onWake == nil && obj.onSleep == nil {
+		return
+	}
+	mode, err := obj.hw.GetMode()
+	if err != nil || mode != hal.ModePowerSave {
+		return
+	}
+	if rising {
+		if obj.onSleep != nil {
+			obj.onSleep(t)
+		}
 		return
 	}
-	obj.onMsgCb(Message{Payload: msg, RSSI: 0}, err)
+	if obj.onWake != nil {
+		obj.onWake(t)
+	}
 }
 
 // readChipRegisters reads all the registers on the chip
-func (obj *Module) readChipRegisters(startingAddress hal.RegAddress, length uint8) (data []byte, err error) {
+func (obj *Module) readChipRegisters(ctx context.Context, startingAddress hal.RegAddress, length uint8) (data []byte, err error) {
+	data, err = obj.queryChip(ctx, []byte{obj.commands.GetReg, startingAddress.ToByte(), length})
+	if err != nil {
+		return data, fmt.Errorf("failed to get config: %w", err)
+	}
+	return
+}
 
-	err = obj.hw.SetMode(hal.ModeSleep)
+// queryChip switches the chip to ModeSleep, writes the command and reads the response. Mode is not restored.
+// Waiting for the module is aborted when ctx is done
+func (obj *Module) queryChip(ctx context.Context, command []byte) (data []byte, err error) {
+	err = obj.checkNotObserver()
+	if err != nil {
+		return data, err
+	}
+	err = obj.setModeContext(ctx, hal.ModeSleep)
 	if err != nil {
-		return data, fmt.Errorf("failed to set chip mode in get config: %w", err)
+		return data, fmt.Errorf("failed to set chip mode: %w", err)
 	}
 
-	err = obj.hw.WriteSerial([]byte{cmdGetReg, startingAddress.ToByte(), length})
+	// drop leftovers from the RX buffer, so that only the response to this command is read
+	// flush must happen before the write, the module can start responding before write done AUX edge
+	err = obj.flushSerial()
 	if err != nil {
-		return data, fmt.Errorf("failed to write get config bytes: %w", err)
+		return data, fmt.Errorf("failed to flush serial: %w", err)
 	}
-	time.Sleep(200 * time.Millisecond)
+	err = obj.writeSerialContext(ctx, command)
+	if err != nil {
+		return data, fmt.Errorf("failed to write command bytes: %w", err)
+	}
+	obj.clock.Sleep(200 * time.Millisecond)
 	data, err = obj.hw.ReadSerial()
 	if err != nil {
-		return data, fmt.Errorf("failed to read config from serial: %w", err)
+		return data, fmt.Errorf("failed to read response from serial: %w", err)
 	}
 	return
 }
 
+// currentRegisters returns a copy of the registers model, safe to use while the config is being updated
+func (obj *Module) currentRegisters() registersCollection {
+	obj.muRegisters.RLock()
+	defer obj.muRegisters.RUnlock()
+	return obj.registers.Copy()
+}
+
 // saveConfig updates lib internal cache with the real registers values on the module
 func (obj *Module) saveConfig(data []byte) error {
 
@@ -145,54 +504,101 @@ func (obj *Module) saveConfig(data []byte) error {
 	if err != nil {
 		return fmt.Errorf("failed to save config: %w", err)
 	}
-	obj.registers.Update(rsp.startAddr, rsp.params)
-	return nil
+	obj.muRegisters.Lock()
+	defer obj.muRegisters.Unlock()
+	return obj.registers.Update(rsp.startAddr, rsp.params)
 }
 
 // getConfigSetRequest returns byte array that holds registers data that must be set
 // temporary construct temporary config that will be reset after chip reboot
 // registers collection of register values that must be set on real module
-func (obj *Module) getConfigSetRequest(temporary bool, registers registersCollection) []byte {
-
-	params := registers[0:]
-	if registers[CRYPT_H].(*CryptH).value == 0 && registers[CRYPT_L].(*CryptL).value == 0 {
-		params = registers[0 : len(registers)-2]
-	}
+// startAddr and length define contiguous range of registers that is written, e.g. C0 04 01 <channel> writes only REG2
+func (obj *Module) getConfigSetRequest(temporary bool, registers registersCollection, startAddr hal.RegAddress, length uint8) []byte {
 	const paramsStartPosition = 3
-	serialDataLen := len(params) + paramsStartPosition
-	//  don't write crypt bytes if not set in new config
-	data := make([]byte, serialDataLen)
-	data[0] = cmdSetRegPermanent
+	data := make([]byte, int(length)+paramsStartPosition)
+	data[0] = obj.commands.SetRegPermanent
 	if temporary {
-		data[0] = cmdSetRegTemporary
+		data[0] = obj.commands.SetRegTemporary
 	}
-	data[1] = ADD_H.ToByte() // start from te first register
-
-	data[2] = byte(len(params)) // data[2] defines param length
+	data[1] = startAddr.ToByte()
+	data[2] = length // data[2] defines param length
 
 	// start from 3, because parameters list starts after cmd, startingAddress, and length bytes
-	for i := 0; i < len(params); i++ {
-		data[i+3] = params[i].GetValue()
+	for i := 0; i < int(length); i++ {
+		data[i+paramsStartPosition] = registerWriteValue(registers[int(startAddr)+i])
 	}
 	return data
 }
 
+// registerWriteValue returns value that is written to the chip, crypt registers are write only and
+// their GetValue always returns 0, so the staged key is used
+func registerWriteValue(reg hal.Register) uint8 {
+	switch r := reg.(type) {
+	case *CryptH:
+		return r.value
+	case *CryptL:
+		return r.value
+	}
+	return reg.GetValue()
+}
+
+// fullWriteLength returns number of registers that are written when the whole config is written,
+// crypt registers are not written if the key is not set
+func (obj *Module) fullWriteLength(registers registersCollection) uint8 {
+	count := int(obj.variant.RegisterCount)
+	if count > len(registers) {
+		count = len(registers)
+	}
+	if count > int(CRYPT_L) && registerWriteValue(registers[CRYPT_H]) == 0 && registerWriteValue(registers[CRYPT_L]) == 0 {
+		count = int(CRYPT_H)
+	}
+	return uint8(count)
+}
+
+// changedRange returns the smallest contiguous range of registers that must be written to get from current to staged
+// config, changed is false if there is nothing to write
+func (obj *Module) changedRange(current registersCollection, staged registersCollection) (startAddr hal.RegAddress, length uint8, changed bool) {
+	first, last := -1, -1
+	for i := 0; i < int(obj.fullWriteLength(staged)); i++ {
+		if registerWriteValue(current[i]) != registerWriteValue(staged[i]) {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+	if first < 0 {
+		return 0, 0, false
+	}
+	return hal.RegAddress(first), uint8(last - first + 1), true
+}
+
 // parseChipResponse when the module is in config mode, it returns response that must be parsed, read datasheet
 // returns new chipRsp object that holds parsed data
 func (obj *Module) parseChipResponse(data []byte) (chipRsp, error) {
 
 	if len(data) < 4 {
-		return chipRsp{}, fmt.Errorf("invalid command")
+		return chipRsp{}, fmt.Errorf("%w: response of %d bytes is too short", ErrInvalidConfigResponse, len(data))
+	}
+	// chip answers every register command with GetReg, it answers with 0xFF bytes if the command was malformed
+	if data[0] != obj.commands.GetReg {
+		return chipRsp{}, fmt.Errorf("%w: got 0x%02X, expected 0x%02X", ErrUnexpectedCommand, data[0], obj.commands.GetReg)
 	}
 	startAddr := data[1]
 	length := data[2]
 	params := data[3:]
 
-	if int(length) != len(params) {
-		return chipRsp{}, fmt.Errorf("invalid command, mismatch in length and params count")
+	if int(length) > len(params) {
+		return chipRsp{}, fmt.Errorf("%w: mismatch in length and params count", ErrInvalidConfigResponse)
+	}
+	if int(startAddr)+int(length) > int(obj.variant.RegisterCount) {
+		return chipRsp{}, fmt.Errorf("%w: registers 0x%02X-0x%02X are out of range, chip has %d registers",
+			ErrInvalidConfigResponse, startAddr, int(startAddr)+int(length)-1, obj.variant.RegisterCount)
 	}
+	// some firmware appends status bytes after the params, ignore everything after the declared length
+	params = params[:length]
 	return chipRsp{
-		command:   cmdGetReg,
+		command:   obj.commands.GetReg,
 		startAddr: startAddr,
 		length:    length,
 		params:    params,
@@ -203,33 +609,179 @@ func (obj *Module) parseChipResponse(data []byte) (chipRsp, error) {
 // update serial config data
 func (obj *Module) updateSerialStreamConfig() error {
 	// get chip serial config and apply it to the serial port handler
-	reg0 := obj.registers[REG0].(*Reg0)
+	reg0 := obj.currentRegisters()[REG0].(*Reg0)
 	baud := serialBaudMap[reg0.baudRate]
 	parity := serialParityMap[reg0.parityBit]
+	if stager, ok := obj.hw.(hal.StopBitsStager); ok {
+		stager.StageSerialStopBits(obj.stopBits)
+	}
 	obj.hw.StageSerialPortConfig(baud, parity)
+	if setter, ok := obj.hw.(hal.AirDataRateSetter); ok {
+		setter.SetAirDataRate(reg0.adRate.BPS())
+	}
+	return nil
+}
+
+// CheckSerialConfig returns error if the serial port params differ from the params that are stored on the chip.
+// Params differ in ModeSleep since the chip is configured on the config mode baud rate, the check is skipped then
+func (obj *Module) CheckSerialConfig() error {
+	mode, err := obj.hw.GetMode()
+	if err != nil {
+		return fmt.Errorf("failed to get chip mode: %w", err)
+	}
+	if mode == hal.ModeSleep {
+		return nil
+	}
+	reg0 := obj.currentRegisters()[REG0].(*Reg0)
+	chipBaud := serialBaudMap[reg0.baudRate]
+	chipParity := serialParityMap[reg0.parityBit]
+	reporter, ok := obj.hw.(hal.SerialConfigReporter)
+	if !ok {
+		return fmt.Errorf("%w: serial port config can't be checked", hal.ErrNotSupported)
+	}
+	baud, parity := reporter.CurrentSerialConfig()
+	if baud != chipBaud || parity != chipParity {
+		return fmt.Errorf("serial port config mismatch, port uses baud %d parity %c, chip uses baud %d parity %c", baud, parity, chipBaud, chipParity)
+	}
 	return nil
 }
 
 // WriteConfigToChip writes given config to module
-func (obj *Module) WriteConfigToChip(temporaryConfig bool, stagedRegisters registersCollection) error {
-	if stagedRegisters.EqualTo(obj.registers) {
-		return fmt.Errorf("new register setup is the same as the setup on the chip, ignoring")
+// the smallest contiguous range of changed registers is written with a single set command, which the chip applies
+// at once, e.g. only REG2 is written when only the channel changes. If the written config can't be
+// verified, the config that was on the chip before the write is written back. The chip mode that was set before
+// the write is restored, also when the write fails.
+// Note that writing a subset of registers with separate set commands is not atomic across registers.
+// Returns false without writing anything if the staged config is the same as the config on the chip,
+// so permanent writes don't wear the chip flash when nothing changes
+func (obj *Module) WriteConfigToChip(temporaryConfig bool, stagedRegisters registersCollection) (bool, error) {
+	// sends and the ambient RSSI sampler must not see the chip in ModeSleep
+	obj.muSend.Lock()
+	defer obj.muSend.Unlock()
+	changed, err := obj.writeConfigToChip(temporaryConfig, stagedRegisters)
+	obj.muRegisters.Lock()
+	obj.lastWriteChanged = changed
+	obj.muRegisters.Unlock()
+	return changed, err
+}
+
+// writeConfigToChip writes given config to module like WriteConfigToChip, caller must hold muSend
+func (obj *Module) writeConfigToChip(temporaryConfig bool, stagedRegisters registersCollection) (changed bool, err error) {
+	startAddr, length, changed := obj.changedRange(obj.currentRegisters(), stagedRegisters)
+	if !changed {
+		return false, nil
+	}
+	err = obj.checkNotObserver()
+	if err != nil {
+		return false, err
 	}
 	currentMode, err := obj.hw.GetMode()
 	if err != nil {
-		return fmt.Errorf("failed to get current chip mode: %w", err)
+		return false, fmt.Errorf("failed to get current chip mode: %w", err)
+	}
+	err = obj.hw.SetMode(hal.ModeSleep)
+	if err != nil {
+		return false, fmt.Errorf("failed to start config builder: %w", err)
+	}
+	// chip must not stay in ModeSleep when the write fails, later sends would fail with a mode error
+	defer func() {
+		restoreErr := obj.hw.SetMode(currentMode)
+		if restoreErr == nil {
+			return
+		}
+		if err == nil {
+			err = fmt.Errorf("failed to set next chip mode: %w", restoreErr)
+			return
+		}
+		err = fmt.Errorf("%w, failed to restore chip mode: %v", err, restoreErr)
+	}()
+	previousRegisters := obj.currentRegisters()
+	err = obj.writeRegisters(temporaryConfig, stagedRegisters, startAddr, length)
+	if err == nil && !stagedRegisters.EqualTo(obj.currentRegisters()) {
+		err = fmt.Errorf("%w: current chip configuration is not the same as saved", ErrConfigMismatch)
+	}
+	if err != nil {
+		// previous crypt key is not known, it can't be restored
+		if startAddr >= CRYPT_H {
+			return false, err
+		}
+		restoreLength := length
+		if int(startAddr)+int(restoreLength) > int(CRYPT_H) {
+			restoreLength = uint8(CRYPT_H - startAddr)
+		}
+		restoreErr := obj.writeRegisters(temporaryConfig, previousRegisters, startAddr, restoreLength)
+		if restoreErr != nil {
+			return false, fmt.Errorf("%w, failed to restore previous config: %v", err, restoreErr)
+		}
+		return false, fmt.Errorf("%w, previous config restored", err)
+	}
+
+	err = obj.updateSerialStreamConfig()
+	if err != nil {
+		return true, fmt.Errorf("failed to update serial port config with the new data: %w", err)
+	}
+	return true, nil
+}
+
+// SetTxPower permanently writes transmitting power to the chip, and returns the power that the chip reports after
+// the write. Some firmware clamps the power to the regional maximum, compare the result with the requested power
+func (obj *Module) SetTxPower(power TransmittingPower) (result TransmittingPower, err error) {
+	err = obj.checkNotObserver()
+	if err != nil {
+		return 0, err
+	}
+	obj.muSend.Lock()
+	defer obj.muSend.Unlock()
+
+	mode, err := obj.hw.GetMode()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get chip mode: %w", err)
 	}
+	stagedRegisters := obj.currentRegisters()
+	stagedRegisters[REG1].(*Reg1).transmittingPower = power
 	err = obj.hw.SetMode(hal.ModeSleep)
 	if err != nil {
-		return fmt.Errorf("failed to start config builder: %w", err)
+		return 0, fmt.Errorf("failed to set chip mode in set tx power: %w", err)
+	}
+	defer func() {
+		restoreErr := obj.hw.SetMode(mode)
+		if restoreErr != nil && err == nil {
+			result, err = 0, fmt.Errorf("failed to restore chip mode after set tx power: %w", restoreErr)
+		}
+	}()
+	err = obj.writeRegisters(false, stagedRegisters, REG1, 1)
+	if err != nil {
+		return 0, err
+	}
+	err = obj.readConfig(context.Background())
+	if err != nil {
+		return 0, fmt.Errorf("failed to read tx power back from the chip: %w", err)
+	}
+	return obj.currentRegisters()[REG1].(*Reg1).transmittingPower, nil
+}
+
+// writeRegisters writes given range of registers to the chip that is in ModeSleep, and saves the chip response to lib model
+func (obj *Module) writeRegisters(temporary bool, registers registersCollection, startAddr hal.RegAddress, length uint8) error {
+	err := obj.checkNotObserver()
+	if err != nil {
+		return err
+	}
+	data := obj.getConfigSetRequest(temporary, registers, startAddr, length)
+	// drop leftovers from the RX buffer, so that only the set config response is read
+	err = obj.flushSerial()
+	if err != nil {
+		return fmt.Errorf("failed to flush serial before set config: %w", err)
 	}
-	data := obj.getConfigSetRequest(temporaryConfig, stagedRegisters)
 	err = obj.hw.WriteSerial(data)
 	if err != nil {
 		return fmt.Errorf("failed to write config to the chip: %w", err)
 	}
-	time.Sleep(200 * time.Millisecond)
+	obj.clock.Sleep(200 * time.Millisecond)
 	chipCfg, err := obj.hw.ReadSerial()
+	if temporary && (err != nil || len(chipCfg) == 0) {
+		// some firmware revisions don't respond to temporary writes, read written registers back instead
+		chipCfg, err = obj.readChipRegisters(context.Background(), startAddr, length)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to receive set config response: %w", err)
 	}
@@ -238,65 +790,403 @@ func (obj *Module) WriteConfigToChip(temporaryConfig bool, stagedRegisters regis
 	if err != nil {
 		return fmt.Errorf("failed to save chip config to lib model: %w", err)
 	}
+	obj.setVolatileConfig(temporary)
+	if int(startAddr)+int(length) > int(CRYPT_H) {
+		obj.muRegisters.Lock()
+		obj.cryptKeySet = true
+		obj.muRegisters.Unlock()
+	}
+	return nil
+}
 
-	err = obj.updateSerialStreamConfig()
+// CryptKeySet returns true if a crypt key was written to the chip by this module instance. Crypt registers are
+// write only, so a key that was set before the module was created (e.g. before a reboot) is not known and false
+// is returned for it
+func (obj *Module) CryptKeySet() bool {
+	obj.muRegisters.RLock()
+	defer obj.muRegisters.RUnlock()
+	return obj.cryptKeySet
+}
+
+// setVolatileConfig saves whether the config on the chip is lost on power cycle
+func (obj *Module) setVolatileConfig(volatile bool) {
+	obj.muRegisters.Lock()
+	defer obj.muRegisters.Unlock()
+	obj.volatileConfig = volatile
+}
+
+// LastWriteWasVolatile returns true if the last config write was temporary, so the config on the chip is lost on
+// power cycle. Returns false if nothing was written since the config was loaded from the chip flash
+func (obj *Module) LastWriteWasVolatile() bool {
+	obj.muRegisters.RLock()
+	defer obj.muRegisters.RUnlock()
+	return obj.volatileConfig
+}
+
+// LastWriteChanged returns true if the last config write changed the config on the chip, false if the chip
+// already had the written config, or the write failed before anything was written
+func (obj *Module) LastWriteChanged() bool {
+	obj.muRegisters.RLock()
+	defer obj.muRegisters.RUnlock()
+	return obj.lastWriteChanged
+}
+
+// checkTransmittable returns error if the chip is in the mode in which it can't transmit
+func (obj *Module) checkTransmittable() error {
+	currentMode, err := obj.hw.GetMode()
 	if err != nil {
-		return fmt.Errorf("failed to update serial port config with the new data: %w", err)
+		return err
 	}
-	if !stagedRegisters.EqualTo(obj.registers) {
-		return fmt.Errorf("current chip configuration is not the same as saved: %w", err)
+	if currentMode == hal.ModeSleep || currentMode == hal.ModePowerSave {
+		return fmt.Errorf("%w: can't send message while E22 module is in mode %d. Change the mode to ModeNormal or ModeWakeUp", ErrModeNotTransmittable, currentMode)
 	}
+	return nil
+}
 
-	err = obj.hw.SetMode(currentMode)
+// checkTransparent returns ErrTransmissionMethodMismatch in TRANSMISSION_FIXED mode, the chip would take the first
+// three payload bytes as address and channel
+func (obj *Module) checkTransparent() error {
+	if obj.currentRegisters()[REG3].(*Reg3).transmissionMethod == TRANSMISSION_FIXED {
+		return fmt.Errorf("%w: can't send message while module has TRANSMISSION_FIXED setup, use SendFixedMessage", ErrTransmissionMethodMismatch)
+	}
+	return nil
+}
+
+// checkFixed returns ErrTransmissionMethodMismatch in TRANSMISSION_TRANSPARENT mode, the chip would transmit
+// the address and channel as a part of the payload
+func (obj *Module) checkFixed() error {
+	if obj.currentRegisters()[REG3].(*Reg3).transmissionMethod == TRANSMISSION_TRANSPARENT {
+		return fmt.Errorf("%w: can't send fixed message while module has TRANSMISSION_TRANSPARENT setup, reconfigure module to TRANSMISSION_FIXED mode", ErrTransmissionMethodMismatch)
+	}
+	return nil
+}
+
+// checkPayloadNotEmpty returns ErrEmptyPayload for empty payload, unless empty payloads are allowed
+func (obj *Module) checkPayloadNotEmpty(payload []byte) error {
+	if len(payload) == 0 && !obj.allowEmptyPayload {
+		return ErrEmptyPayload
+	}
+	return nil
+}
+
+// checkPayloadSize returns error if the payload is empty, or longer than firmware can transmit in one frame
+func (obj *Module) checkPayloadSize(payload []byte) error {
+	err := obj.checkPayloadNotEmpty(payload)
 	if err != nil {
-		return fmt.Errorf("failed to set nextchip mode %w", err)
+		return err
+	}
+	if obj.maxPayloadSize > 0 && len(payload) > obj.maxPayloadSize {
+		return fmt.Errorf("payload of %d bytes exceeds firmware max payload size of %d bytes", len(payload), obj.maxPayloadSize)
 	}
 	return nil
 }
 
-// SendMessage sends given message to module via UART
-func (obj *Module) SendMessage(message string) error {
-	currentMode, err := obj.hw.GetMode()
+// writeMessage checks chip mode and writes given data to the module
+func (obj *Module) writeMessage(data []byte) error {
+	return obj.writeChecked(func() error {
+		obj.recordTx(data)
+		return obj.hw.WriteSerial(data)
+	})
+}
+
+// writeChecked checks chip mode, resets the chip after too many busy timeouts, and performs the given write
+func (obj *Module) writeChecked(write func() error) error {
+	err := obj.checkNotObserver()
 	if err != nil {
 		return err
 	}
-	if currentMode == hal.ModeSleep || currentMode == hal.ModePowerSave {
-		return fmt.Errorf("can't send message while chip is in mode %d. Change mode to ModeNormal or ModeWakeUp", currentMode)
+	err = obj.checkTransmittable()
+	if err != nil {
+		return err
+	}
+	if obj.resetThreshold > 0 && obj.busyTimeouts >= obj.resetThreshold {
+		obj.busyTimeouts = 0
+		err = obj.reset()
+		if obj.onReset != nil {
+			obj.onReset(err)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to reset the chip after busy timeouts: %w", err)
+		}
+	}
+	err = write()
+	if errors.Is(err, hal.ErrChipBusyTimeout) {
+		obj.busyTimeouts++
+	} else if err == nil {
+		obj.busyTimeouts = 0
 	}
-	err = obj.hw.WriteSerial([]byte(message))
 	if err != nil {
-		return fmt.Errorf("failed to write config to the chip: %w", err)
+		return &transmitError{err: fmt.Errorf("failed to write message to the chip: %w", err)}
+	}
+	return nil
+}
+
+// Reset sends software reset command (C4 C4 C4) to the chip in ModeSleep, reloads the config and restores
+// the previous mode. Use it to recover a module that stopped responding properly. Sends are blocked during the reset
+func (obj *Module) Reset() error {
+	obj.muSend.Lock()
+	defer obj.muSend.Unlock()
+	return obj.reset()
+}
+
+// reset resets the chip, caller must hold muSend
+func (obj *Module) reset() error {
+	err := obj.checkNotObserver()
+	if err != nil {
+		return err
+	}
+	mode, err := obj.hw.GetMode()
+	if err != nil {
+		return fmt.Errorf("failed to get chip mode: %w", err)
+	}
+	err = obj.hw.SetMode(hal.ModeSleep)
+	if err != nil {
+		return fmt.Errorf("failed to set chip mode in reset: %w", err)
+	}
+	err = obj.hw.WriteSerial([]byte{obj.commands.Reset, obj.commands.Reset, obj.commands.Reset})
+	if err != nil {
+		return fmt.Errorf("failed to write reset command: %w", err)
+	}
+	// module runs self check after reset, AUX is low until it is done
+	obj.clock.Sleep(1 * time.Second)
+	err = obj.waitAUXIdle(2 * time.Second)
+	if err != nil && !errors.Is(err, hal.ErrNotSupported) {
+		return fmt.Errorf("module is not ready after reset: %w", err)
+	}
+	err = obj.readConfigFromChip()
+	if err != nil {
+		return fmt.Errorf("failed to reload config after reset: %w", err)
+	}
+	// chip loads the permanent config on reset
+	obj.setVolatileConfig(false)
+	err = obj.hw.SetMode(mode)
+	if err != nil {
+		return fmt.Errorf("failed to restore chip mode after reset: %w", err)
+	}
+	return nil
+}
+
+// MaxPayloadSize returns max number of bytes that the module transmits in one packet, the sub-packet size
+// that is configured on the chip, or the firmware max payload size if it is set and smaller
+func (obj *Module) MaxPayloadSize() int {
+	size := subPacketSizeMap[obj.currentRegisters()[REG1].(*Reg1).subPacket]
+	if obj.maxPayloadSize > 0 && obj.maxPayloadSize < size {
+		size = obj.maxPayloadSize
+	}
+	return size
+}
+
+// SendMessage sends given message to module via UART, see SendBytes
+func (obj *Module) SendMessage(message string) error {
+	return obj.SendBytes([]byte(message))
+}
+
+// SendBytes sends given payload to module via UART.
+// Payload that is longer than MaxPayloadSize is split into chunks, each chunk is written when the module is idle.
+// Empty payload is rejected with ErrEmptyPayload, unless the module is created with WithEmptyPayload
+func (obj *Module) SendBytes(data []byte) error {
+	err := obj.checkPayloadNotEmpty(data)
+	if err != nil {
+		return err
+	}
+	obj.muSend.Lock()
+	defer obj.muSend.Unlock()
+	err = obj.checkTransparent()
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return obj.writeMessage(data)
+	}
+	chunkSize := obj.MaxPayloadSize()
+	for start := 0; start < len(data); start += chunkSize {
+		end := start + chunkSize
+		if end > len(data) {
+			end = len(data)
+		}
+		err := obj.writeMessage(data[start:end])
+		if err != nil {
+			return fmt.Errorf("failed to send bytes %d-%d: %w", start, end, err)
+		}
 	}
 	return nil
 }
 
 // SendFixedMessage if you want to send message to some fixed address and channel, use this method
 func (obj *Module) SendFixedMessage(addressHigh byte, addressLow byte, channel byte, message string) error {
-	currentMode, err := obj.hw.GetMode()
+	return obj.SendFixedBytes(addressHigh, addressLow, channel, []byte(message))
+}
+
+// SendFixedBytes sends given payload to the fixed address and channel
+func (obj *Module) SendFixedBytes(addressHigh byte, addressLow byte, channel byte, payload []byte) error {
+	obj.muSend.Lock()
+	defer obj.muSend.Unlock()
+	err := obj.checkFixed()
 	if err != nil {
 		return err
 	}
-	if currentMode == hal.ModeSleep || currentMode == hal.ModePowerSave {
-		return fmt.Errorf("can't send message while E22 module is in mode %d. Change the mode to ModeNormal or ModeWakeUp", currentMode)
-	}
-	if obj.registers[REG3].(*Reg3).transmissionMethod == TRANSMISSION_TRANSPARENT {
-		return fmt.Errorf("can't send fixed message while module has TRANSMISSION_TRANSPARENT setup, reconfigure module to TRANSMISSION_FIXED mode")
+	err = obj.checkPayloadSize(payload)
+	if err != nil {
+		return err
 	}
+	return obj.writeMessage(obj.FixedMessageBytes(addressHigh, addressLow, channel, payload))
+}
+
+// FixedMessageBytes returns the frame that SendFixedBytes writes to the chip, target address and channel followed
+// by the payload. Nothing is sent, use it to check the addressing against the datasheet
+func (obj *Module) FixedMessageBytes(addressHigh byte, addressLow byte, channel byte, payload []byte) []byte {
 	msgBytes := []byte{addressHigh, addressLow, channel}
-	msgBytes = append(msgBytes, []byte(message)...)
+	return append(msgBytes, payload...)
+}
+
+// BroadcastChannels sends payload to the broadcast address (0xFFFF) on every given channel, one channel after another.
+// Fixed transmission sets the channel per message, so the channel of this module is not changed
+func (obj *Module) BroadcastChannels(channels []uint8, payload []byte) error {
+	for _, channel := range channels {
+		err := obj.SendFixedBytes(0xFF, 0xFF, channel, payload)
+		if err != nil {
+			return fmt.Errorf("failed to broadcast on channel %d: %w", channel, err)
+		}
+	}
+	return nil
+}
 
-	err = obj.hw.WriteSerial(msgBytes)
+// SendAndSleep sends given payload and puts the module to ModeSleep right after the module reports that it is done.
+// Other sends are blocked until the module is asleep. Use it on battery nodes to minimize time spent awake
+func (obj *Module) SendAndSleep(payload []byte) error {
+	obj.muSend.Lock()
+	defer obj.muSend.Unlock()
+	err := obj.checkTransparent()
 	if err != nil {
-		return fmt.Errorf("failed to write config to the chip: %w", err)
+		return err
+	}
+	err = obj.checkPayloadSize(payload)
+	if err != nil {
+		return err
+	}
+	err = obj.writeMessage(payload)
+	if err != nil {
+		return err
+	}
+	err = obj.WaitTxComplete(obj.txCompleteTimeout())
+	if err != nil {
+		return err
+	}
+	err = obj.hw.SetMode(hal.ModeSleep)
+	if err != nil {
+		return fmt.Errorf("failed to put the chip to sleep after send: %w", err)
 	}
 	return nil
 }
 
-// GetModuleConfiguration returns human readable current module configuration
+// SendBurst writes frames back to back, waiting for the module to be idle only before the first frame and after
+// the last one. Each frame is transmitted as a separate packet. All frames together must fit into the module buffer
+func (obj *Module) SendBurst(frames [][]byte) error {
+	if len(frames) == 0 {
+		return nil
+	}
+	obj.muSend.Lock()
+	defer obj.muSend.Unlock()
+	err := obj.checkTransparent()
+	if err != nil {
+		return err
+	}
+	for i, frame := range frames {
+		err := obj.checkPayloadSize(frame)
+		if err != nil {
+			return fmt.Errorf("frame %d: %w", i, err)
+		}
+	}
+	return obj.writeChecked(func() error {
+		return obj.writeSerialBurst(frames)
+	})
+}
+
+// SendRaw writes payload to the chip as is. Options that change sent data are not applied, only the chip mode and
+// payload size are checked, in TRANSMISSION_FIXED mode the payload must start with the address and channel. Use it to mix own protocol frames with the library managed traffic
+func (obj *Module) SendRaw(payload []byte) error {
+	obj.muSend.Lock()
+	defer obj.muSend.Unlock()
+	err := obj.checkPayloadSize(payload)
+	if err != nil {
+		return err
+	}
+	return obj.writeMessage(payload)
+}
+
+// WaitTxComplete waits until the module finishes the over the air transmission of the sent data.
+// Send methods return when the module takes the data, which can be long before the transmission ends at low air data rates
+func (obj *Module) WaitTxComplete(timeout time.Duration) error {
+	err := obj.waitAUXIdle(timeout)
+	if err != nil {
+		return fmt.Errorf("failed to wait for transmission to complete: %w", err)
+	}
+	return nil
+}
+
+// txCompleteTimeout returns time in which the module should transmit a full sub-packet, with a margin
+func (obj *Module) txCompleteTimeout() time.Duration {
+	registers := obj.currentRegisters()
+	return maxFrameTime(registers[REG1].(*Reg1).subPacket, registers[REG0].(*Reg0).adRate) + 2*time.Second
+}
+
+// SendReader reads the payload from r and sends it as a single frame.
+// Error is returned if r yields more bytes than fit into the sub-packet that is configured on the chip
+func (obj *Module) SendReader(r io.Reader) error {
+	limit := subPacketSizeMap[obj.currentRegisters()[REG1].(*Reg1).subPacket]
+	payload, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if err != nil {
+		return fmt.Errorf("failed to read payload: %w", err)
+	}
+	if len(payload) > limit {
+		return fmt.Errorf("payload exceeds sub-packet size of %d bytes", limit)
+	}
+	obj.muSend.Lock()
+	defer obj.muSend.Unlock()
+	err = obj.checkTransparent()
+	if err != nil {
+		return err
+	}
+	err = obj.checkPayloadSize(payload)
+	if err != nil {
+		return err
+	}
+	return obj.writeMessage(payload)
+}
+
+// AsRegisterCommands returns hex encoded set config commands for the current config, permanent (C0) and temporary (C2)
+// one, in the format that EBYTE config tool uses (e.g. "C0 00 06 ..."). Use it to compare the config with the vendor tool
+func (obj *Module) AsRegisterCommands() []string {
+	registers := obj.currentRegisters()
+	commands := make([]string, 0, 2)
+	for _, temporary := range []bool{false, true} {
+		data := obj.getConfigSetRequest(temporary, registers, ADD_H, obj.fullWriteLength(registers))
+		hex := make([]string, len(data))
+		for i, b := range data {
+			hex[i] = fmt.Sprintf("%02X", b)
+		}
+		commands = append(commands, strings.Join(hex, " "))
+	}
+	return commands
+}
+
+// Address16 returns module address that is read from the chip, ADD_H is the high byte and ADD_L is the low byte
+func (obj *Module) Address16() uint16 {
+	registers := obj.currentRegisters()
+	return uint16(registers[ADD_H].GetValue())<<8 | uint16(registers[ADD_L].GetValue())
+}
+
+// GetModuleConfiguration returns human readable current module configuration.
+// CRYPT line reports only keys written by this module instance, see CryptKeySet
 func (obj *Module) GetModuleConfiguration() string {
 	var conf string
-	for _, reg := range obj.registers {
+	for _, reg := range obj.currentRegisters() {
 		conf = conf + fmt.Sprintf("\nREG [%d]: %+v", reg.GetAddress(), reg)
 	}
+	if obj.CryptKeySet() {
+		conf = conf + "\nCRYPT: set (write-only)"
+	} else {
+		conf = conf + "\nCRYPT: none"
+	}
 	return conf
 }
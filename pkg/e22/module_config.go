@@ -0,0 +1,69 @@
+package e22
+
+// ModuleConfig typed snapshot of the module config, it is the same type as FullConfig
+type ModuleConfig = FullConfig
+
+// GetConfig returns config that was last read from the chip, the chip is not accessed
+func (obj *Module) GetConfig() ModuleConfig {
+	return newFullConfig(obj.currentRegisters())
+}
+
+// Channel returns channel that was last read from the chip
+func (obj *Module) Channel() uint8 {
+	return obj.GetConfig().RadioParams.Channel
+}
+
+// Address returns high and low address bytes that were last read from the chip
+func (obj *Module) Address() (uint8, uint8) {
+	config := obj.GetConfig()
+	return config.Address.High, config.Address.Low
+}
+
+// AirDataRate returns air data rate that was last read from the chip
+func (obj *Module) AirDataRate() AirDataRate {
+	return obj.GetConfig().RadioParams.AirDataRate
+}
+
+// BaudRate returns serial baud rate that was last read from the chip
+func (obj *Module) BaudRate() BaudRate {
+	return obj.GetConfig().SerialParams.BaudRate
+}
+
+// TransmittingPower returns transmitting power that was last read from the chip
+func (obj *Module) TransmittingPower() TransmittingPower {
+	return obj.GetConfig().RadioParams.TransmittingPower
+}
+
+// RSSIEnabled returns true if the chip appends RSSI byte to the received data
+func (obj *Module) RSSIEnabled() bool {
+	return obj.GetConfig().TransmissionParams.RSSI == RSSI_ENABLE
+}
+
+// TransmissionMethod returns transmission method that was last read from the chip
+func (obj *Module) TransmissionMethod() TransmissionMethod {
+	return obj.GetConfig().TransmissionParams.Method
+}
+
+// WORCycle returns WOR cycle that was last read from the chip
+func (obj *Module) WORCycle() WORCycle {
+	return obj.GetConfig().TransmissionParams.WORCycle
+}
+
+// factoryChannels factory default channel per band, by base frequency
+var factoryChannels = map[float64]uint8{
+	DefaultBaseFrequencyMHz: 0x12, // 868.125 MHz
+	410.125:                 0x17, // 433.125 MHz
+}
+
+// LoadDefaults returns ConfigBuilder with EBYTE factory config staged, see DefaultModuleConfig. Default channel
+// depends on the band, it is staged for 900 MHz and 400 MHz modules (by WithBaseFrequency), other bands keep the
+// current channel. Nothing is written, change what is needed and call a write method of the returned builder
+func (obj *Module) LoadDefaults() *ConfigBuilder {
+	config := DefaultModuleConfig
+	channel, ok := factoryChannels[obj.baseFrequency]
+	if !ok {
+		channel = obj.Channel()
+	}
+	config.RadioParams.Channel = channel
+	return NewConfigBuilder(obj).FullConfig(config)
+}
@@ -0,0 +1,338 @@
+package e22
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mbalug7/go-ebyte-lora/pkg/hal"
+	"github.com/tarm/serial"
+)
+
+// fakeHW hardware handler that records writes and returns queued reads
+type fakeHW struct {
+	mu       sync.Mutex
+	mode     hal.ChipMode
+	writes   [][]byte
+	writeErr error
+	reads    [][]byte
+	onMsg    hal.OnMessageCb
+	onAux    hal.OnAuxEdgeCb
+	bauds    []int // config mode baud rates that are set
+}
+
+func (obj *fakeHW) ReadSerial() ([]byte, error) {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	if len(obj.reads) == 0 {
+		return []byte{}, nil
+	}
+	data := obj.reads[0]
+	obj.reads = obj.reads[1:]
+	return data, nil
+}
+
+func (obj *fakeHW) WriteSerial(msg []byte) error {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	obj.writes = append(obj.writes, append([]byte(nil), msg...))
+	return obj.writeErr
+}
+
+func (obj *fakeHW) WriteSerialContext(ctx context.Context, msg []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return obj.WriteSerial(msg)
+}
+
+func (obj *fakeHW) WriteSerialBurst(frames [][]byte) error {
+	for _, frame := range frames {
+		err := obj.WriteSerial(frame)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (obj *fakeHW) FlushSerial() error {
+	return nil
+}
+
+func (obj *fakeHW) StageSerialPortConfig(baudRate int, parityBit serial.Parity) {
+}
+
+func (obj *fakeHW) CurrentSerialConfig() (int, serial.Parity) {
+	return 9600, serial.ParityNone
+}
+
+func (obj *fakeHW) SetConfigModeBaud(baudRate int) error {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	obj.bauds = append(obj.bauds, baudRate)
+	return nil
+}
+
+func (obj *fakeHW) SetAirDataRate(bitsPerSecond int) {
+}
+
+func (obj *fakeHW) SetMode(mode hal.ChipMode) error {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	obj.mode = mode
+	return nil
+}
+
+func (obj *fakeHW) SetModeContext(ctx context.Context, mode hal.ChipMode) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return obj.SetMode(mode)
+}
+
+func (obj *fakeHW) WaitAUXIdle(timeout time.Duration) error {
+	return nil
+}
+
+func (obj *fakeHW) GetMode() (hal.ChipMode, error) {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	return obj.mode, nil
+}
+
+func (obj *fakeHW) RegisterOnMessageCb(cb hal.OnMessageCb) error {
+	obj.onMsg = cb
+	return nil
+}
+
+func (obj *fakeHW) RegisterOnAuxEdgeCb(cb hal.OnAuxEdgeCb) error {
+	obj.onAux = cb
+	return nil
+}
+
+// setWriteErr sets error that is returned by the following writes
+func (obj *fakeHW) setWriteErr(err error) {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	obj.writeErr = err
+}
+
+// writeCount returns number of writes
+func (obj *fakeHW) writeCount() int {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	return len(obj.writes)
+}
+
+// newTestModule constructs module in ModeNormal on top of the fake handler, config is not read from the chip
+func newTestModule(t *testing.T, cb OnMessageCb, opts ...ModuleOption) (*Module, *fakeHW) {
+	t.Helper()
+	hw := &fakeHW{mode: hal.ModeNormal}
+	module := newModule(hw, cb, opts)
+	t.Cleanup(module.Close)
+	return module, hw
+}
+
+// setTransmissionMethod sets transmission method in the local registers model
+func (obj *Module) setTransmissionMethod(method TransmissionMethod) {
+	obj.muRegisters.Lock()
+	defer obj.muRegisters.Unlock()
+	obj.registers[REG3].(*Reg3).transmissionMethod = method
+}
+
+func TestOnMessageHandlerCallsCallbackOncePerFrame(t *testing.T) {
+	readErr := errors.New("read failed")
+	tests := []struct {
+		name   string
+		data   []byte
+		err    error
+		calls  int
+		expErr error
+	}{
+		{name: "frame", data: []byte("hello"), calls: 1},
+		{name: "error with empty payload", data: []byte{}, err: readErr, calls: 1, expErr: readErr},
+		{name: "error with nil payload", err: readErr, calls: 1, expErr: readErr},
+		{name: "error with payload", data: []byte("partial"), err: readErr, calls: 1, expErr: readErr},
+		{name: "read timeout", data: []byte{}, err: io.EOF, calls: 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var msgs []Message
+			var errs []error
+			module, _ := newTestModule(t, func(msg Message, err error) {
+				msgs = append(msgs, msg)
+				errs = append(errs, err)
+			})
+			module.onMessageHandler(test.data, test.err)
+			if len(msgs) != test.calls {
+				t.Fatalf("callback called %d times, expected %d", len(msgs), test.calls)
+			}
+			if test.calls == 0 {
+				return
+			}
+			if !errors.Is(errs[0], test.expErr) {
+				t.Fatalf("callback error is %v, expected %v", errs[0], test.expErr)
+			}
+			if test.expErr == nil && !bytes.Equal(msgs[0].Payload, test.data) {
+				t.Fatalf("payload is %q, expected %q", msgs[0].Payload, test.data)
+			}
+		})
+	}
+}
+
+func TestOnMessageHandlerCallsCallbackForEveryFrame(t *testing.T) {
+	calls := 0
+	module, _ := newTestModule(t, func(msg Message, err error) {
+		calls++
+	})
+	frames := [][]byte{[]byte("one"), []byte("two"), []byte("three")}
+	for _, frame := range frames {
+		module.onMessageHandler(frame, nil)
+	}
+	module.onMessageHandler([]byte{}, errors.New("read failed"))
+	if calls != len(frames)+1 {
+		t.Fatalf("callback called %d times, expected %d", calls, len(frames)+1)
+	}
+}
+
+func TestInitConfigDetectsBaudOnce(t *testing.T) {
+	clock := hal.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	module, hw := newTestModule(t, nil, WithClock(clock), WithInitTimeout(2*time.Second), WithBaudDetection())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- module.initConfig(context.Background())
+	}()
+	var err error
+	for finished := false; !finished; {
+		select {
+		case err = <-done:
+			finished = true
+		default:
+			if clock.Waiters() > 0 {
+				clock.Advance(100 * time.Millisecond)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	if err == nil {
+		t.Fatal("config is read from a module that doesn't respond")
+	}
+	hw.mu.Lock()
+	defer hw.mu.Unlock()
+	if len(hw.bauds) != len(configBaudCandidates)+1 {
+		t.Fatalf("config mode baud rate is set %d times, expected one detection pass of %d", len(hw.bauds), len(configBaudCandidates)+1)
+	}
+	handshakes := len(hw.writes) - len(configBaudCandidates)
+	if handshakes < 2 {
+		t.Fatalf("initial config read is tried %d times, expected it to be retried", handshakes)
+	}
+}
+
+func TestInitConfigWithoutBaudDetection(t *testing.T) {
+	module, hw := newTestModule(t, nil)
+	err := module.initConfig(context.Background())
+	if err == nil {
+		t.Fatal("config is read from a module that doesn't respond")
+	}
+	if len(hw.bauds) != 0 {
+		t.Fatalf("config mode baud rate is changed without baud detection: %v", hw.bauds)
+	}
+	if len(hw.writes) != 1 {
+		t.Fatalf("config is read %d times, expected once", len(hw.writes))
+	}
+}
+
+func TestTransparentSendsRejectFixedMode(t *testing.T) {
+	module, hw := newTestModule(t, nil)
+	module.setTransmissionMethod(TRANSMISSION_FIXED)
+	payload := []byte("hello")
+	sends := map[string]func() error{
+		"SendBytes":     func() error { return module.SendBytes(payload) },
+		"SendAndSleep":  func() error { return module.SendAndSleep(payload) },
+		"SendBurst":     func() error { return module.SendBurst([][]byte{payload}) },
+		"SendReader":    func() error { return module.SendReader(bytes.NewReader(payload)) },
+		"SendAddressed": func() error { return module.SendAddressed(0x0001, payload) },
+	}
+	for name, send := range sends {
+		if err := send(); !errors.Is(err, ErrTransmissionMethodMismatch) {
+			t.Errorf("%s: expected ErrTransmissionMethodMismatch, got: %v", name, err)
+		}
+	}
+	if hw.writeCount() != 0 {
+		t.Fatalf("%d writes in fixed mode, expected none", hw.writeCount())
+	}
+}
+
+func TestSetTxPowerRestoresModeOnError(t *testing.T) {
+	module, hw := newTestModule(t, nil)
+	hw.setWriteErr(errors.New("write failed"))
+	_, err := module.SetTxPower(TP_17_DBM)
+	if err == nil {
+		t.Fatal("set tx power succeeded while writes fail")
+	}
+	if mode, _ := hw.GetMode(); mode != hal.ModeNormal {
+		t.Fatalf("chip is left in mode %d, expected ModeNormal", mode)
+	}
+}
+
+func TestWriteConfigToChipRestoresModeOnError(t *testing.T) {
+	module, hw := newTestModule(t, nil)
+	hw.setWriteErr(errors.New("write failed"))
+	staged := module.currentRegisters()
+	staged[REG2].SetValue(0x12)
+	_, err := module.WriteConfigToChip(false, staged)
+	if err == nil {
+		t.Fatal("config write succeeded while writes fail")
+	}
+	if mode, _ := hw.GetMode(); mode != hal.ModeNormal {
+		t.Fatalf("chip is left in mode %d, expected ModeNormal", mode)
+	}
+}
+
+func TestConfigOperationsWaitForSendLock(t *testing.T) {
+	module, hw := newTestModule(t, nil)
+	ops := map[string]func(){
+		"ReadConfigFromChip": func() { module.ReadConfigFromChip() },
+		"WriteConfigToChip": func() {
+			staged := module.currentRegisters()
+			staged[REG2].SetValue(0x12)
+			module.WriteConfigToChip(true, staged)
+		},
+		"ReadProductInfo": func() { module.ReadProductInfo() },
+		"SetTxPower":      func() { module.SetTxPower(TP_13_DBM) },
+	}
+	for name, op := range ops {
+		writes := hw.writeCount()
+		module.muSend.Lock()
+		done := make(chan struct{})
+		go func() {
+			op()
+			close(done)
+		}()
+		time.Sleep(20 * time.Millisecond)
+		if hw.writeCount() != writes {
+			t.Errorf("%s wrote to the chip while the send lock is held", name)
+		}
+		module.muSend.Unlock()
+		<-done
+	}
+}
+
+func TestNewModuleContextCanceled(t *testing.T) {
+	hw := &fakeHW{mode: hal.ModeNormal}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := NewModuleContext(ctx, hw, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got: %v", err)
+	}
+	if hw.writeCount() != 0 {
+		t.Fatalf("%d writes after the context is canceled, expected none", hw.writeCount())
+	}
+}
@@ -0,0 +1,35 @@
+package e22
+
+import (
+	"fmt"
+
+	"github.com/mbalug7/go-ebyte-lora/pkg/hal"
+)
+
+// NewObserverModule constructs module that passively receives messages, without reading the config from the chip.
+// The config must be the config that is on the chip, it is used to parse received data (RSSI byte, addressing).
+// Observer never changes chip mode nor writes to the chip, such operations return ErrObserverModule.
+// Given hardware handler must not drive mode lines (create it with common.WithPassiveModeLines or common.WithoutGPIO),
+// handler that reports that it drives them is rejected. Its serial port must already use the chip baud rate and parity
+func NewObserverModule(gpioHandler hal.HWHandler, cb OnMessageCb, config FullConfig, opts ...ModuleOption) (*Module, error) {
+	if driver, ok := gpioHandler.(hal.ModeLineDriver); ok && driver.DrivesModeLines() {
+		return nil, fmt.Errorf("observer module requires hardware handler that doesn't drive mode lines")
+	}
+	ch := newModule(gpioHandler, cb, opts)
+	ch.observer = true
+	config.stage(ch.registers)
+	err := gpioHandler.RegisterOnMessageCb(ch.onMessageHandler)
+	if err != nil {
+		ch.Close()
+		return nil, fmt.Errorf("failed to register OnMessageCb: %w", err)
+	}
+	return ch, nil
+}
+
+// checkNotObserver returns error if the module is an observer
+func (obj *Module) checkNotObserver() error {
+	if obj.observer {
+		return ErrObserverModule
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package e22
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mbalug7/go-ebyte-lora/pkg/hal"
+)
+
+// modeLineHW fake handler that reports whether it drives mode lines
+type modeLineHW struct {
+	*fakeHW
+	drives bool
+}
+
+func (obj *modeLineHW) DrivesModeLines() bool {
+	return obj.drives
+}
+
+func TestObserverRejectsHandlerThatDrivesModeLines(t *testing.T) {
+	config := newFullConfig(newRegistersCollection())
+	_, err := NewObserverModule(&modeLineHW{fakeHW: &fakeHW{}, drives: true}, nil, config)
+	if err == nil {
+		t.Fatal("observer is created with the handler that drives mode lines")
+	}
+
+	hw := &modeLineHW{fakeHW: &fakeHW{mode: hal.ModeNormal}}
+	module, err := NewObserverModule(hw, nil, config)
+	if err != nil {
+		t.Fatalf("failed to create observer with passive mode lines: %v", err)
+	}
+	defer module.Close()
+	if err := module.ReadConfigFromChip(); !errors.Is(err, ErrObserverModule) {
+		t.Fatalf("expected ErrObserverModule, got: %v", err)
+	}
+}
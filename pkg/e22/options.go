@@ -0,0 +1,228 @@
+package e22
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/mbalug7/go-ebyte-lora/pkg/hal"
+	"github.com/tarm/serial"
+)
+
+// ModuleOption defines optional Module behaviour that can be passed to NewModule
+type ModuleOption func(*Module)
+
+// WithLineDelimiter reassembles received transparent mode data into frames terminated by the given delimiter.
+// The delimiter is not part of the delivered Message payload
+func WithLineDelimiter(delimiter byte) ModuleOption {
+	return func(obj *Module) {
+		obj.framer = &delimiterFramer{delimiter: delimiter}
+	}
+}
+
+// WithLengthPrefix reassembles received transparent mode data into frames that start with one length byte.
+// The length byte is not part of the delivered Message payload
+func WithLengthPrefix() ModuleOption {
+	return func(obj *Module) {
+		obj.framer = &lengthPrefixFramer{}
+	}
+}
+
+// WithPowerSaveEvents registers callbacks that are called when the module in ModePowerSave (WOR receiver) wakes up
+// to receive data and when it goes back to sleep. Messages received in the wake window are delivered to the message
+// callback between these two events. Any of the callbacks can be nil. Events need a handler that implements
+// hal.AuxEdgeNotifier
+func WithPowerSaveEvents(onWake func(time.Time), onSleep func(time.Time)) ModuleOption {
+	return func(obj *Module) {
+		obj.onWake = onWake
+		obj.onSleep = onSleep
+	}
+}
+
+// WithSerialStopBits sets number of serial stop bits that is used outside of ModeSleep.
+// E22 uses 1 stop bit, change it only for UART bridges or custom firmware that need 2. Stop bits are applied only
+// by handlers that implement hal.StopBitsStager
+func WithSerialStopBits(stopBits serial.StopBits) ModuleOption {
+	return func(obj *Module) {
+		obj.stopBits = stopBits
+	}
+}
+
+// WithIdempotentWrites when enabled, ConfigBuilder WritePermanentConfig and WriteTemporaryConfig return nil if the staged
+// config is already applied on the chip, instead of the "same as the setup on the chip" error
+func WithIdempotentWrites(enabled bool) ModuleOption {
+	return func(obj *Module) {
+		obj.idempotentWrites = enabled
+	}
+}
+
+// WithPayloadSizeHistogram collects received payload lengths, read them with Module.PayloadSizeHistogram.
+// Use it to check if the configured sub-packet size fits the real traffic
+func WithPayloadSizeHistogram() ModuleOption {
+	return func(obj *Module) {
+		obj.payloadSizes = make(map[int]int)
+	}
+}
+
+// WithAutoReset resets the chip with the software reset command before the next send, when threshold consecutive sends
+// failed because the module never released AUX line. onReset is called with the reset result and can be nil.
+// Reset can't help if the firmware doesn't accept commands anymore, in that case the module must be power cycled
+func WithAutoReset(threshold int, onReset func(error)) ModuleOption {
+	return func(obj *Module) {
+		obj.resetThreshold = threshold
+		obj.onReset = onReset
+	}
+}
+
+// WithMaxPayloadSize sets max payload length that the module firmware transmits in one frame. Some firmware versions
+// cap the frame below the sub-packet size and silently truncate longer payloads, with this option such sends fail.
+// The limit is not detected from the chip (neither from ReadProductInfo nor from the variant), without this option
+// only the sub-packet size limits the payload. Check the datasheet of your module firmware for the value
+func WithMaxPayloadSize(size int) ModuleOption {
+	return func(obj *Module) {
+		obj.maxPayloadSize = size
+	}
+}
+
+// WithRxAddressFilter adds software addressing on top of the transparent transmission. Every received frame must start
+// with 2 byte big endian destination address, as sent by SendAddressed. Frames that are not addressed to addr or to
+// BroadcastAddress are dropped, the address header is stripped from the delivered payload.
+// Combine it with a framing option if one transmission can be received in more than one chunk
+func WithRxAddressFilter(addr uint16) ModuleOption {
+	return func(obj *Module) {
+		obj.rxAddressFilter = true
+		obj.rxAddress = addr
+	}
+}
+
+// WithVariant sets chip register layout, VariantE22 is used by default
+func WithVariant(variant Variant) ModuleOption {
+	return func(obj *Module) {
+		obj.variant = variant
+	}
+}
+
+// WithReceiveQueue decouples the message callback from the reception. Received messages are queued, and the callback
+// is called from a separate goroutine, so a slow callback doesn't stall the reading of next frames.
+// When the queue holds size messages, policy decides what happens with the next one. Call Module.Close to stop the queue
+func WithReceiveQueue(size int, policy OverflowPolicy) ModuleOption {
+	return func(obj *Module) {
+		obj.rxQueueSize = size
+		obj.rxPolicy = policy
+	}
+}
+
+// WithFrameRecorder writes every chunk of data received from the chip to w, as JSON lines with the receive time.
+// Use ReplayFrames to feed the capture to a module later
+func WithFrameRecorder(w io.Writer) ModuleOption {
+	return func(obj *Module) {
+		obj.recorder = &frameRecorder{enc: json.NewEncoder(w)}
+	}
+}
+
+// WithInitTimeout retries the initial config read in NewModule until timeout expires, instead of failing after the
+// first attempt. Use it for modules that need more time after power up. Runtime send and mode switch timeouts are not affected
+func WithInitTimeout(timeout time.Duration) ModuleOption {
+	return func(obj *Module) {
+		obj.initTimeout = timeout
+	}
+}
+
+// WithBaudDetection tries all supported config mode baud rates if the initial config read fails, and keeps the first one
+// on which the module responds with a valid config. Use it for modules with custom firmware that doesn't use 9600 in
+// ModeSleep. Without it the error of the initial config read is returned
+func WithBaudDetection() ModuleOption {
+	return func(obj *Module) {
+		obj.baudDetection = true
+	}
+}
+
+// WithAsyncCallback calls the message callback from a pool of workers goroutines, instead of the goroutine that reads
+// the chip. With ordered delivery a single worker is used, so messages are handled one by one in the receive order.
+// Unordered delivery lets a slow message not hold back the next ones, but the callback must be safe for concurrent use.
+// Messages are passed to workers through the receive queue, its size and policy can be set with WithReceiveQueue,
+// otherwise the queue blocks reception when it is full. Call Module.Close to stop the workers
+func WithAsyncCallback(workers int, ordered bool) ModuleOption {
+	return func(obj *Module) {
+		obj.rxWorkers = workers
+		if ordered {
+			obj.rxWorkers = 1
+		}
+		if obj.rxQueueSize <= 0 {
+			obj.rxQueueSize = defaultAsyncQueueSize
+			obj.rxPolicy = OverflowBlock
+		}
+	}
+}
+
+// WithPeerRSSI overrides the decision whether the last byte of the received data is RSSI. By default the RSSI setting
+// of this module is used, and the last byte is stripped from every message when RSSI is enabled. If the data doesn't
+// carry the RSSI byte while the setting says it does (e.g. mismatched setup in a mixed network), the last payload
+// byte is silently lost. Set enabled to match what is actually received
+func WithPeerRSSI(enabled bool) ModuleOption {
+	return func(obj *Module) {
+		obj.peerRSSI = &enabled
+	}
+}
+
+// WithCommandSet overrides register command bytes, DefaultCommandSet is used by default. No EBYTE firmware revision
+// is known to need it, it is meant for rebranded and clone modules whose documentation lists different command bytes
+func WithCommandSet(commands CommandSet) ModuleOption {
+	return func(obj *Module) {
+		obj.commands = commands
+	}
+}
+
+// WithMinFirmwareVersion fails the feature with ErrUnsupportedFirmware on modules whose ReadProductInfo version is
+// lower than version. The version is read from the chip once, before the first use of the feature.
+// EBYTE doesn't publish which firmware revision added a feature, so no version is required by default
+func WithMinFirmwareVersion(feature Feature, version byte) ModuleOption {
+	return func(obj *Module) {
+		if obj.minFirmware == nil {
+			obj.minFirmware = make(map[Feature]byte)
+		}
+		obj.minFirmware[feature] = version
+	}
+}
+
+// WithClock replaces the clock that is used for timeouts and delays, use hal.FakeClock in tests
+func WithClock(clock hal.Clock) ModuleOption {
+	return func(obj *Module) {
+		obj.clock = clock
+	}
+}
+
+// WithEchoSuppression drops received data that is equal to the data written to the chip within the last window.
+// Some half-duplex adapters echo the written bytes back to the serial RX line, and the echo would be delivered as
+// a received message. Dropped echoes are counted, see Module.EchoesSuppressed
+func WithEchoSuppression(window time.Duration) ModuleOption {
+	return func(obj *Module) {
+		obj.echoWindow = window
+	}
+}
+
+// WithBaseFrequency sets frequency of the channel 0 in MHz, DefaultBaseFrequencyMHz is used by default.
+// Check the datasheet of your module band variant for the value (e.g. 410.125 for 400 MHz modules)
+func WithBaseFrequency(mhz float64) ModuleOption {
+	return func(obj *Module) {
+		obj.baseFrequency = mhz
+	}
+}
+
+// WithAmbientRSSISampling reads ambient noise RSSI every interval and keeps the last size samples, see
+// Module.RecentAmbientRSSI. Samples are taken only while ambient noise RSSI is enabled on the chip, the module is
+// in ModeNormal or ModeWakeUp, and the chip is idle. Sampling waits for sends in progress. Call Module.Close to stop it
+func WithAmbientRSSISampling(interval time.Duration, size int) ModuleOption {
+	return func(obj *Module) {
+		obj.ambientInterval = interval
+		obj.ambientSamples = make([]uint8, size)
+	}
+}
+
+// WithEmptyPayload allows sending empty payloads, e.g. as keepalive frames. By default sends of empty payloads
+// fail with ErrEmptyPayload, since some firmware rejects empty writes
+func WithEmptyPayload() ModuleOption {
+	return func(obj *Module) {
+		obj.allowEmptyPayload = true
+	}
+}
@@ -0,0 +1,52 @@
+package e22
+
+import (
+	"context"
+	"fmt"
+)
+
+// productInfoResponseSize size of the product info response, 3 header bytes and 4 info bytes
+const productInfoResponseSize = 7
+
+// ProductInfo model and version of the module
+type ProductInfo struct {
+	Model    byte
+	Version  byte
+	Features byte
+	Raw      []byte // whole response, for the bytes that are not parsed
+}
+
+// ReadProductInfo sends C1 C1 C1 to the chip in ModeSleep and parses the product info from the response.
+// Response starts with the 3 byte header (C1 followed by 2 bytes), model, version and features follow it.
+// The previous mode is restored
+func (obj *Module) ReadProductInfo() (info ProductInfo, err error) {
+	obj.muSend.Lock()
+	defer obj.muSend.Unlock()
+	mode, err := obj.hw.GetMode()
+	if err != nil {
+		return ProductInfo{}, fmt.Errorf("failed to get chip mode: %w", err)
+	}
+	// query switches the chip to ModeSleep, the mode is restored even if the query fails
+	defer func() {
+		restoreErr := obj.hw.SetMode(mode)
+		if restoreErr != nil && err == nil {
+			info, err = ProductInfo{}, fmt.Errorf("failed to restore chip mode after product info read: %w", restoreErr)
+		}
+	}()
+	data, err := obj.queryChip(context.Background(), []byte{obj.commands.GetReg, obj.commands.GetReg, obj.commands.GetReg})
+	if err != nil {
+		return ProductInfo{}, fmt.Errorf("failed to read product info: %w", err)
+	}
+	if len(data) < productInfoResponseSize {
+		return ProductInfo{}, fmt.Errorf("%w: truncated product info response, got %d bytes, expected %d", ErrInvalidConfigResponse, len(data), productInfoResponseSize)
+	}
+	if data[0] != obj.commands.GetReg {
+		return ProductInfo{}, fmt.Errorf("%w: got 0x%02X, expected 0x%02X", ErrUnexpectedCommand, data[0], obj.commands.GetReg)
+	}
+	return ProductInfo{
+		Model:    data[3],
+		Version:  data[4],
+		Features: data[5],
+		Raw:      data,
+	}, nil
+}
@@ -0,0 +1,49 @@
+package e22
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mbalug7/go-ebyte-lora/pkg/hal"
+)
+
+func TestReadProductInfoRestoresMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		writeErr error
+		response []byte
+		expErr   error
+	}{
+		{name: "write error", writeErr: errors.New("write failed")},
+		{name: "truncated response", response: []byte{0xC1, 0x00, 0x07}, expErr: ErrInvalidConfigResponse},
+		{name: "unexpected command", response: []byte{0xFF, 0x00, 0x07, 0x22, 0x10, 0x00, 0x00}, expErr: ErrUnexpectedCommand},
+		{name: "valid response", response: []byte{0xC1, 0x00, 0x07, 0x22, 0x10, 0x01, 0x00}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			module, hw := newTestModule(t, nil)
+			hw.setWriteErr(test.writeErr)
+			if test.response != nil {
+				hw.reads = [][]byte{test.response}
+			}
+			info, err := module.ReadProductInfo()
+			switch {
+			case test.writeErr != nil:
+				if !errors.Is(err, test.writeErr) {
+					t.Fatalf("expected write error, got: %v", err)
+				}
+			case test.expErr != nil:
+				if !errors.Is(err, test.expErr) {
+					t.Fatalf("expected %v, got: %v", test.expErr, err)
+				}
+			default:
+				if err != nil || info.Model != 0x22 || info.Version != 0x10 {
+					t.Fatalf("unexpected product info %+v, err: %v", info, err)
+				}
+			}
+			if mode, _ := hw.GetMode(); mode != hal.ModeNormal {
+				t.Fatalf("chip is left in mode %d, expected ModeNormal", mode)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package e22
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultBaseFrequencyMHz frequency of the channel 0 on 900 MHz modules, Actual frequency = 850.125 + CH *1M.
+// Other band variants have a different base frequency, set it with WithBaseFrequency
+const DefaultBaseFrequencyMHz = 850.125
+
+// Region defines frequency band in which transmission is allowed in some regulatory region
+type Region struct {
+	Name     string
+	MinMHz   float64
+	MaxMHz   float64
+	MaxDwell time.Duration // max on air time of a single transmission, 0 if the region has no dwell time limit
+}
+
+// regions supported by the 900 MHz E22 modules, check local regulations before using them
+var (
+	RegionEU868 = Region{Name: "EU868", MinMHz: 863, MaxMHz: 870}
+	RegionIN865 = Region{Name: "IN865", MinMHz: 865, MaxMHz: 867}
+	RegionUS915 = Region{Name: "US915", MinMHz: 902, MaxMHz: 928, MaxDwell: 400 * time.Millisecond}
+	RegionAU915 = Region{Name: "AU915", MinMHz: 915, MaxMHz: 928, MaxDwell: 400 * time.Millisecond}
+	RegionKR920 = Region{Name: "KR920", MinMHz: 920.9, MaxMHz: 923.3}
+)
+
+// channelFrequencyMHz returns channel center frequency
+func (obj *Module) channelFrequencyMHz(channel uint8) float64 {
+	return obj.baseFrequency + float64(channel)
+}
+
+// ChannelFrequencyMHz returns center frequency of the channel that was last read from the chip
+func (obj *Module) ChannelFrequencyMHz() float64 {
+	return obj.channelFrequencyMHz(obj.Channel())
+}
+
+// ValidateChannelForRegion checks if the frequency of the channel that is set on the chip is in the given region band
+func (obj *Module) ValidateChannelForRegion(region Region) error {
+	channel := obj.currentRegisters()[REG2].GetValue()
+	frequency := obj.channelFrequencyMHz(channel)
+	if frequency < region.MinMHz || frequency > region.MaxMHz {
+		return fmt.Errorf("channel %d (%.3f MHz) is outside of the %s band %.3f-%.3f MHz",
+			channel, frequency, region.Name, region.MinMHz, region.MaxMHz)
+	}
+	return nil
+}
+
+// airDataRateBPSMap air data rate in bits per second
+var airDataRateBPSMap = map[AirDataRate]int{
+	ADR_2400_0: 2400,
+	ADR_2400_1: 2400,
+	ADR_2400:   2400,
+	ADR_4800:   4800,
+	ADR_9600:   9600,
+	ADR_19200:  19200,
+	ADR_38400:  38400,
+	ADR_62500:  62500,
+}
+
+// maxFrameTime returns on air time of the full sub-packet at the given air data rate.
+// LoRa preamble and header are not included, so the real frame time is a bit longer
+func maxFrameTime(packet SubPacket, rate AirDataRate) time.Duration {
+	bits := subPacketSizeMap[packet] * 8
+	return time.Duration(bits) * time.Second / time.Duration(rate.BPS())
+}
+
+// ValidateDwellTime checks if the full sub-packet transmission at the air data rate that is set on the chip
+// fits into the region dwell time limit. Use a smaller sub-packet or a higher air data rate if it doesn't
+func (obj *Module) ValidateDwellTime(region Region) error {
+	if region.MaxDwell == 0 {
+		return nil
+	}
+	registers := obj.currentRegisters()
+	packet := registers[REG1].(*Reg1).subPacket
+	rate := registers[REG0].(*Reg0).adRate
+	frameTime := maxFrameTime(packet, rate)
+	if frameTime > region.MaxDwell {
+		return fmt.Errorf("%d byte sub-packet at %d bps takes %s on air, %s dwell time limit is %s",
+			subPacketSizeMap[packet], rate.BPS(), frameTime, region.Name, region.MaxDwell)
+	}
+	return nil
+}
@@ -1,6 +1,8 @@
 package e22
 
 import (
+	"fmt"
+
 	"github.com/mbalug7/go-ebyte-lora/pkg/hal"
 )
 
@@ -47,10 +49,14 @@ func (obj registersCollection) Copy() registersCollection {
 // Update updates register collection
 // startAddr address from where we want to update register collection
 // params-> new values that are set to registers
-func (obj registersCollection) Update(startAddr byte, params []byte) {
+func (obj registersCollection) Update(startAddr byte, params []byte) error {
+	if int(startAddr)+len(params) > len(obj) {
+		return fmt.Errorf("can't update %d registers from address 0x%02X, collection holds %d registers", len(params), startAddr, len(obj))
+	}
 	for i := 0; i < len(params); i++ {
 		obj[int(startAddr)+i].SetValue(params[i])
 	}
+	return nil
 }
 
 const (
@@ -100,31 +106,44 @@ func (obj *AddL) SetValue(value uint8) {
 }
 
 // REG0 specification
-type baudRate uint8
+type BaudRate uint8
 
 const (
-	BAUD_1200   baudRate = 0x00
-	BAUD_2400   baudRate = 0x20
-	BAUD_4800   baudRate = 0x40
-	BAUD_9600   baudRate = 0x60
-	BAUD_19200  baudRate = 0x80
-	BAUD_38400  baudRate = 0xA0
-	BAUD_57600  baudRate = 0xC0
-	BAUD_115200 baudRate = 0xE0
+	BAUD_1200   BaudRate = 0x00
+	BAUD_2400   BaudRate = 0x20
+	BAUD_4800   BaudRate = 0x40
+	BAUD_9600   BaudRate = 0x60
+	BAUD_19200  BaudRate = 0x80
+	BAUD_38400  BaudRate = 0xA0
+	BAUD_57600  BaudRate = 0xC0
+	BAUD_115200 BaudRate = 0xE0
 )
 
-type parity uint8
+type Parity uint8
 
 const (
-	PARITY_8N1 parity = 0x00
-	PARITY_8O1 parity = 0x08
-	PARITY_8E1 parity = 0x10
+	PARITY_8N1 Parity = 0x00
+	PARITY_8O1 Parity = 0x08
+	PARITY_8E1 Parity = 0x10
 )
 
-type airDataRate uint8
+// String returns parity in the human readable form, e.g. 8N1
+func (obj Parity) String() string {
+	switch obj {
+	case PARITY_8N1, 0x18: // datasheet defines 11 as 8N1, the same as 00
+		return "8N1"
+	case PARITY_8O1:
+		return "8O1"
+	case PARITY_8E1:
+		return "8E1"
+	}
+	return fmt.Sprintf("unknown parity 0x%02X", uint8(obj))
+}
+
+type AirDataRate uint8
 
 const (
-	ADR_2400_0 airDataRate = iota
+	ADR_2400_0 AirDataRate = iota
 	ADR_2400_1
 	ADR_2400
 	ADR_4800
@@ -134,10 +153,25 @@ const (
 	ADR_62500
 )
 
+// BPS returns air data rate in bits per second, 0 for an unknown value.
+// ADR_2400_0, ADR_2400_1 and ADR_2400 are all 2400 bps, the datasheet defines three codes for it
+func (obj AirDataRate) BPS() int {
+	return airDataRateBPSMap[obj]
+}
+
+// String returns air data rate in the human readable form, e.g. 2400 bps
+func (obj AirDataRate) String() string {
+	bps := obj.BPS()
+	if bps == 0 {
+		return fmt.Sprintf("unknown air data rate 0x%02X", uint8(obj))
+	}
+	return fmt.Sprintf("%d bps", bps)
+}
+
 type Reg0 struct {
-	baudRate  baudRate
-	parityBit parity
-	adRate    airDataRate
+	baudRate  BaudRate
+	parityBit Parity
+	adRate    AirDataRate
 }
 
 func (obj *Reg0) GetAddress() hal.RegAddress {
@@ -149,41 +183,46 @@ func (obj *Reg0) GetValue() uint8 {
 }
 
 func (obj *Reg0) SetValue(value uint8) {
-	obj.baudRate = baudRate(value & 0xE0)  // get last 3 bits
-	obj.parityBit = parity(value & 0x18)   // git bit 3 and 4
-	obj.adRate = airDataRate(value & 0x07) // get first 3 bits
+	obj.baudRate = BaudRate(value & 0xE0)  // get last 3 bits
+	obj.parityBit = Parity(value & 0x18)   // git bit 3 and 4
+	obj.adRate = AirDataRate(value & 0x07) // get first 3 bits
+}
+
+// String returns REG0 values in the human readable form
+func (obj *Reg0) String() string {
+	return fmt.Sprintf("{baudRate:%d parityBit:%s adRate:%s}", serialBaudMap[obj.baudRate], obj.parityBit, obj.adRate)
 }
 
 // REG1 specification
-type subPacket uint8
+type SubPacket uint8
 
 const (
-	BYTES_200 subPacket = 0x00
-	BYTES_128 subPacket = 0x40
-	BYTES_64  subPacket = 0x80
-	BYTES_32  subPacket = 0xC0
+	BYTES_200 SubPacket = 0x00
+	BYTES_128 SubPacket = 0x40
+	BYTES_64  SubPacket = 0x80
+	BYTES_32  SubPacket = 0xC0
 )
 
-type rssiAmbientNoise uint8
+type RSSIAmbientNoise uint8
 
 const (
-	RSSI_AMBIENT_NOISE_DISABLE rssiAmbientNoise = 0x00
-	RSSI_AMBIENT_NOISE_ENABLE  rssiAmbientNoise = 0x20
+	RSSI_AMBIENT_NOISE_DISABLE RSSIAmbientNoise = 0x00
+	RSSI_AMBIENT_NOISE_ENABLE  RSSIAmbientNoise = 0x20
 )
 
-type transmittingPower uint8
+type TransmittingPower uint8
 
 const (
-	TP_22_DBM transmittingPower = iota
+	TP_22_DBM TransmittingPower = iota
 	TP_17_DBM
 	TP_13_DBM
 	TP_10_DBM
 )
 
 type Reg1 struct {
-	subPacket         subPacket
-	ambientNoiseRSSI  rssiAmbientNoise
-	transmittingPower transmittingPower
+	subPacket         SubPacket
+	ambientNoiseRSSI  RSSIAmbientNoise
+	transmittingPower TransmittingPower
 }
 
 func (obj *Reg1) GetAddress() hal.RegAddress {
@@ -195,9 +234,9 @@ func (obj *Reg1) GetValue() uint8 {
 }
 
 func (obj *Reg1) SetValue(value uint8) {
-	obj.subPacket = subPacket(value & 0xC0)
-	obj.ambientNoiseRSSI = rssiAmbientNoise(value & 0x20)
-	obj.transmittingPower = transmittingPower(value & 0x03)
+	obj.subPacket = SubPacket(value & 0xC0)
+	obj.ambientNoiseRSSI = RSSIAmbientNoise(value & 0x20)
+	obj.transmittingPower = TransmittingPower(value & 0x03)
 }
 
 // REG2 specification
@@ -222,31 +261,31 @@ func (obj *Reg2) SetValue(value uint8) {
 }
 
 // REG3 specification
-type enableRSSI uint8
+type EnableRSSI uint8
 
 const (
-	RSSI_DISABLE enableRSSI = 0x00
-	RSSI_ENABLE  enableRSSI = 0x80
+	RSSI_DISABLE EnableRSSI = 0x00
+	RSSI_ENABLE  EnableRSSI = 0x80
 )
 
-type transmissionMethod uint8
+type TransmissionMethod uint8
 
 const (
-	TRANSMISSION_TRANSPARENT transmissionMethod = 0x00
-	TRANSMISSION_FIXED       transmissionMethod = 0x40
+	TRANSMISSION_TRANSPARENT TransmissionMethod = 0x00
+	TRANSMISSION_FIXED       TransmissionMethod = 0x40
 )
 
-type lbt uint8
+type LBT uint8
 
 const (
-	LBT_DISABLE lbt = 0x00
-	LBT_ENABLE  lbt = 0x08
+	LBT_DISABLE LBT = 0x00
+	LBT_ENABLE  LBT = 0x08
 )
 
-type worCycle uint8
+type WORCycle uint8
 
 const (
-	WOR_500_MS worCycle = iota
+	WOR_500_MS WORCycle = iota
 	WOR_1000_MS
 	WOR_1500_MS
 	WOR_2000_MS
@@ -257,10 +296,10 @@ const (
 )
 
 type Reg3 struct {
-	enableRSSI         enableRSSI
-	transmissionMethod transmissionMethod
-	lbtEnable          lbt
-	worCycle           worCycle
+	enableRSSI         EnableRSSI
+	transmissionMethod TransmissionMethod
+	lbtEnable          LBT
+	worCycle           WORCycle
 }
 
 func (obj *Reg3) GetAddress() hal.RegAddress {
@@ -272,10 +311,10 @@ func (obj *Reg3) GetValue() uint8 {
 }
 
 func (obj *Reg3) SetValue(value uint8) {
-	obj.enableRSSI = enableRSSI(value & 0x80)
-	obj.transmissionMethod = transmissionMethod(value & 0x40)
-	obj.lbtEnable = lbt(value & 0x08)
-	obj.worCycle = worCycle(value & 0x07)
+	obj.enableRSSI = EnableRSSI(value & 0x80)
+	obj.transmissionMethod = TransmissionMethod(value & 0x40)
+	obj.lbtEnable = LBT(value & 0x08)
+	obj.worCycle = WORCycle(value & 0x07)
 }
 
 // CRYPT_H specification
@@ -0,0 +1,57 @@
+package e22
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// recordedFrame single received chunk in the frame capture, one JSON object per line
+type recordedFrame struct {
+	Time time.Time `json:"time"`
+	Data []byte    `json:"data"` // raw data as read from the chip, including RSSI byte
+}
+
+// frameRecorder writes received data to the capture
+type frameRecorder struct {
+	enc *json.Encoder
+	mu  sync.Mutex
+}
+
+// record writes received data to the capture, write errors are ignored so that the recording never affects reception
+func (obj *frameRecorder) record(data []byte, t time.Time) {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	_ = obj.enc.Encode(recordedFrame{Time: t, Data: data})
+}
+
+// ReplayFrames feeds frames captured with WithFrameRecorder into the module message handling, as if they were received
+// from the chip. Time between frames is preserved. Module config (RSSI, framing, filters) must match the recorded one
+func ReplayFrames(m *Module, r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var previous time.Time
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var frame recordedFrame
+		err := json.Unmarshal(scanner.Bytes(), &frame)
+		if err != nil {
+			return fmt.Errorf("failed to decode frame on line %d: %w", line, err)
+		}
+		if !previous.IsZero() && frame.Time.After(previous) {
+			m.clock.Sleep(frame.Time.Sub(previous))
+		}
+		previous = frame.Time
+		m.onMessageHandler(frame.Data, nil)
+	}
+	err := scanner.Err()
+	if err != nil {
+		return fmt.Errorf("failed to read frame capture: %w", err)
+	}
+	return nil
+}
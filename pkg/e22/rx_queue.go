@@ -0,0 +1,152 @@
+package e22
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// defaultAsyncQueueSize receive queue size that is used by WithAsyncCallback if the queue size is not set
+const defaultAsyncQueueSize = 64
+
+// OverflowPolicy defines what happens with a received message when the receive queue is full
+type OverflowPolicy int
+
+const (
+	// OverflowDropOldest drops the oldest queued message to make room for the new one
+	OverflowDropOldest OverflowPolicy = iota
+	// OverflowDropNewest drops the new message
+	OverflowDropNewest
+	// OverflowBlock blocks reception until the callback takes a message from the queue
+	OverflowBlock
+)
+
+// rxItem received message or reception error that waits in the receive queue
+type rxItem struct {
+	msg Message
+	err error
+}
+
+// startRxQueue starts the receive queue workers if the queue is enabled
+func (obj *Module) startRxQueue() {
+	if obj.rxQueueSize <= 0 {
+		return
+	}
+	obj.rxQueue = make(chan rxItem, obj.rxQueueSize)
+	obj.rxQueueStop = make(chan struct{})
+	obj.rxQueueDone = make(chan struct{})
+	workers := obj.rxWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			obj.runRxWorker()
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(obj.rxQueueDone)
+	}()
+}
+
+// runRxWorker calls the message callback for queued messages until the queue is stopped, messages that are
+// already queued when the queue is stopped are delivered before it returns
+func (obj *Module) runRxWorker() {
+	for {
+		select {
+		case item := <-obj.rxQueue:
+			obj.callRxCb(item)
+		case <-obj.rxQueueStop:
+			for {
+				select {
+				case item := <-obj.rxQueue:
+					obj.callRxCb(item)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// callRxCb calls the message callback for the queued item, running callbacks are counted so that Close called
+// from the callback doesn't wait for itself
+func (obj *Module) callRxCb(item rxItem) {
+	atomic.AddInt32(&obj.rxCallbacks, 1)
+	defer atomic.AddInt32(&obj.rxCallbacks, -1)
+	obj.onMsgCb(item.msg, item.err)
+}
+
+// stopRxQueue stops the receive queue and waits until the queued messages are delivered. It doesn't wait if a
+// callback is running, the callback may be the one that called Close
+func (obj *Module) stopRxQueue() {
+	if obj.rxQueue == nil {
+		return
+	}
+	obj.rxStopOnce.Do(func() {
+		close(obj.rxQueueStop)
+	})
+	if atomic.LoadInt32(&obj.rxCallbacks) > 0 {
+		return
+	}
+	<-obj.rxQueueDone
+}
+
+// dispatch passes received message to the callback directly, or through the receive queue if it is enabled.
+// Messages received after the queue is stopped are dropped
+func (obj *Module) dispatch(msg Message, err error) {
+	if obj.rxQueue == nil {
+		obj.onMsgCb(msg, err)
+		return
+	}
+	select {
+	case <-obj.rxQueueStop:
+		return
+	default:
+	}
+	item := rxItem{msg: msg, err: err}
+	switch obj.rxPolicy {
+	case OverflowBlock:
+		select {
+		case obj.rxQueue <- item:
+		case <-obj.rxQueueStop:
+		}
+		return
+	case OverflowDropNewest:
+		select {
+		case obj.rxQueue <- item:
+		default:
+			obj.countRxOverflow()
+		}
+		return
+	}
+	for {
+		select {
+		case obj.rxQueue <- item:
+			return
+		default:
+		}
+		select {
+		case <-obj.rxQueue:
+			obj.countRxOverflow()
+		default:
+		}
+	}
+}
+
+// RxQueueOverflows returns number of received messages that were dropped because the receive queue was full
+func (obj *Module) RxQueueOverflows() uint64 {
+	obj.muStats.Lock()
+	defer obj.muStats.Unlock()
+	return obj.rxOverflows
+}
+
+// countRxOverflow increments receive queue overflow counter
+func (obj *Module) countRxOverflow() {
+	obj.muStats.Lock()
+	defer obj.muStats.Unlock()
+	obj.rxOverflows++
+}
@@ -0,0 +1,132 @@
+package e22
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitClosed fails the test if ch is not closed within a second
+func waitClosed(t *testing.T, ch <-chan struct{}, what string) {
+	t.Helper()
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Fatalf("%s didn't return", what)
+	}
+}
+
+func TestCloseFromQueuedCallback(t *testing.T) {
+	closed := make(chan struct{})
+	var module *Module
+	module, _ = newTestModule(t, func(msg Message, err error) {
+		module.Close()
+		close(closed)
+	}, WithAsyncCallback(1, true))
+
+	module.onMessageHandler([]byte("hello"), nil)
+	waitClosed(t, closed, "Close called from the callback")
+	waitClosed(t, module.rxQueueDone, "receive worker")
+}
+
+func TestCloseWithFullBlockingQueue(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	module, _ := newTestModule(t, func(msg Message, err error) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+	}, WithReceiveQueue(1, OverflowBlock))
+
+	module.onMessageHandler([]byte("one"), nil)
+	<-started
+	module.onMessageHandler([]byte("two"), nil)
+	received := make(chan struct{})
+	go func() {
+		module.onMessageHandler([]byte("three"), nil)
+		close(received)
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	closed := make(chan struct{})
+	go func() {
+		module.Close()
+		close(closed)
+	}()
+	waitClosed(t, closed, "Close")
+	waitClosed(t, received, "blocked reception")
+	close(release)
+	waitClosed(t, module.rxQueueDone, "receive worker")
+}
+
+func TestReceiveQueueOverflowPolicies(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    OverflowPolicy
+		delivered []string
+		overflows uint64
+	}{
+		{name: "block", policy: OverflowBlock, delivered: []string{"one", "two", "three", "four"}},
+		{name: "drop newest", policy: OverflowDropNewest, delivered: []string{"one", "two", "three"}, overflows: 1},
+		{name: "drop oldest", policy: OverflowDropOldest, delivered: []string{"one", "three", "four"}, overflows: 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var delivered []string
+			started := make(chan struct{}, 1)
+			release := make(chan struct{})
+			module, _ := newTestModule(t, func(msg Message, err error) {
+				mu.Lock()
+				delivered = append(delivered, string(msg.Payload))
+				mu.Unlock()
+				select {
+				case started <- struct{}{}:
+				default:
+				}
+				<-release
+			}, WithReceiveQueue(2, test.policy))
+
+			// the callback holds "one", "two" and "three" fill the queue, "four" overflows it
+			module.onMessageHandler([]byte("one"), nil)
+			<-started
+			module.onMessageHandler([]byte("two"), nil)
+			module.onMessageHandler([]byte("three"), nil)
+			received := make(chan struct{})
+			go func() {
+				module.onMessageHandler([]byte("four"), nil)
+				close(received)
+			}()
+			select {
+			case <-received:
+				if test.policy == OverflowBlock {
+					t.Fatal("reception is not blocked by the full queue")
+				}
+			case <-time.After(20 * time.Millisecond):
+				if test.policy != OverflowBlock {
+					t.Fatal("reception is blocked by the full queue")
+				}
+			}
+			close(release)
+			waitClosed(t, received, "reception")
+			module.Close()
+			waitClosed(t, module.rxQueueDone, "receive worker")
+
+			mu.Lock()
+			defer mu.Unlock()
+			if len(delivered) != len(test.delivered) {
+				t.Fatalf("delivered %q, expected %q", delivered, test.delivered)
+			}
+			for i := range delivered {
+				if delivered[i] != test.delivered[i] {
+					t.Fatalf("delivered %q, expected %q", delivered, test.delivered)
+				}
+			}
+			if module.RxQueueOverflows() != test.overflows {
+				t.Fatalf("%d overflows counted, expected %d", module.RxQueueOverflows(), test.overflows)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package e22
+
+// rxWaiter waits for one received message that satisfies the match function
+type rxWaiter struct {
+	match func(Message) bool
+	ch    chan Message
+}
+
+// addRxWaiter registers a new waiter, matched message is delivered to the waiter instead of the message callback
+func (obj *Module) addRxWaiter(match func(Message) bool) *rxWaiter {
+	waiter := &rxWaiter{match: match, ch: make(chan Message, 1)}
+	obj.muRxWaiters.Lock()
+	defer obj.muRxWaiters.Unlock()
+	obj.rxWaiters = append(obj.rxWaiters, waiter)
+	return waiter
+}
+
+// removeRxWaiter removes waiter that is not needed anymore
+func (obj *Module) removeRxWaiter(waiter *rxWaiter) {
+	obj.muRxWaiters.Lock()
+	defer obj.muRxWaiters.Unlock()
+	for i, w := range obj.rxWaiters {
+		if w == waiter {
+			obj.rxWaiters = append(obj.rxWaiters[:i], obj.rxWaiters[i+1:]...)
+			return
+		}
+	}
+}
+
+// offerToRxWaiter passes the message to the first waiter that expects it, returns false if there is no such waiter
+func (obj *Module) offerToRxWaiter(msg Message) bool {
+	obj.muRxWaiters.Lock()
+	defer obj.muRxWaiters.Unlock()
+	for i, w := range obj.rxWaiters {
+		if w.match(msg) {
+			w.ch <- msg
+			obj.rxWaiters = append(obj.rxWaiters[:i], obj.rxWaiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package e22
+
+import "time"
+
+// recordRx saves the receive time, and adds received payload length to the histogram if histogram collection is enabled
+func (obj *Module) recordRx(size int) {
+	obj.muStats.Lock()
+	defer obj.muStats.Unlock()
+	obj.lastRx = obj.clock.Now()
+	if obj.payloadSizes == nil {
+		return
+	}
+	obj.payloadSizes[size]++
+}
+
+// PayloadSizeHistogram returns number of received messages per payload length.
+// Returns nil if the module is not created with WithPayloadSizeHistogram
+func (obj *Module) PayloadSizeHistogram() map[int]int {
+	obj.muStats.Lock()
+	defer obj.muStats.Unlock()
+	if obj.payloadSizes == nil {
+		return nil
+	}
+	histogram := make(map[int]int, len(obj.payloadSizes))
+	for size, count := range obj.payloadSizes {
+		histogram[size] = count
+	}
+	return histogram
+}
+
+// LastRxTime returns time when the last message was received, zero time if nothing is received yet
+func (obj *Module) LastRxTime() time.Time {
+	obj.muStats.Lock()
+	defer obj.muStats.Unlock()
+	return obj.lastRx
+}
+
+// TimeSinceLastRx returns time since the last message was received, or since the module was created if nothing
+// is received yet. Poll it to detect a silent link
+func (obj *Module) TimeSinceLastRx() time.Duration {
+	obj.muStats.Lock()
+	defer obj.muStats.Unlock()
+	if obj.lastRx.IsZero() {
+		return obj.clock.Now().Sub(obj.created)
+	}
+	return obj.clock.Now().Sub(obj.lastRx)
+}
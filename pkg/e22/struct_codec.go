@@ -0,0 +1,86 @@
+package e22
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+)
+
+// structByteOrder returns byte order of the struct field, set with `ebyte:"le"` or `ebyte:"be"` tag. Big endian is default
+func structByteOrder(field reflect.StructField) (binary.ByteOrder, error) {
+	switch tag := field.Tag.Get("ebyte"); tag {
+	case "", "be":
+		return binary.BigEndian, nil
+	case "le":
+		return binary.LittleEndian, nil
+	default:
+		return nil, fmt.Errorf("field %s has invalid ebyte tag %q, expected be or le", field.Name, tag)
+	}
+}
+
+// EncodeStruct packs exported fields of the struct into bytes, in the declaration order.
+// Fields must have fixed size (bool, sized ints, floats, arrays of them).
+// Every field is big endian unless tagged with `ebyte:"le"`
+func EncodeStruct(v interface{}) ([]byte, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("expected struct, got %T", v)
+	}
+	var buf bytes.Buffer
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if field.PkgPath != "" {
+			continue // unexported
+		}
+		order, err := structByteOrder(field)
+		if err != nil {
+			return nil, err
+		}
+		err = binary.Write(&buf, order, rv.Field(i).Interface())
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode field %s: %w", field.Name, err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// DecodeStruct unpacks bytes produced by EncodeStruct into the struct that v points to.
+// Use it on the receiver side with the same struct definition as the sender
+func DecodeStruct(data []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("expected pointer to struct, got %T", v)
+	}
+	rv = rv.Elem()
+	r := bytes.NewReader(data)
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if field.PkgPath != "" {
+			continue // unexported
+		}
+		order, err := structByteOrder(field)
+		if err != nil {
+			return err
+		}
+		err = binary.Read(r, order, rv.Field(i).Addr().Interface())
+		if err != nil {
+			return fmt.Errorf("failed to decode field %s: %w", field.Name, err)
+		}
+	}
+	if r.Len() != 0 {
+		return fmt.Errorf("%d trailing bytes after decoding %s", r.Len(), rt.Name())
+	}
+	return nil
+}
+
+// SendFixedStruct encodes the struct with EncodeStruct and sends it to the target as a fixed message
+func (obj *Module) SendFixedStruct(target Target, v interface{}) error {
+	payload, err := EncodeStruct(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode payload: %w", err)
+	}
+	return obj.SendFixedBytes(target.AddressHigh, target.AddressLow, target.Channel, payload)
+}
@@ -0,0 +1,32 @@
+package e22
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Transact sends req, and returns the payload of the first message that is received after it, within replyTimeout.
+// The reply is consumed by Transact and is not passed to the message callback. Transactions don't overlap, concurrent
+// calls wait for each other. Use it for strict request/response protocols where nothing else is sent on the channel
+func (obj *Module) Transact(ctx context.Context, req []byte, replyTimeout time.Duration) ([]byte, error) {
+	obj.muTransact.Lock()
+	defer obj.muTransact.Unlock()
+	// register before sending, so that a fast reply is not passed to the message callback
+	waiter := obj.addRxWaiter(func(Message) bool { return true })
+	err := obj.SendBytes(req)
+	if err != nil {
+		obj.removeRxWaiter(waiter)
+		return nil, fmt.Errorf("failed to send transaction request: %w", err)
+	}
+	select {
+	case <-ctx.Done():
+		obj.removeRxWaiter(waiter)
+		return nil, ctx.Err()
+	case <-obj.clock.After(replyTimeout):
+		obj.removeRxWaiter(waiter)
+		return nil, fmt.Errorf("no reply received within %s", replyTimeout)
+	case msg := <-waiter.ch:
+		return msg.Payload, nil
+	}
+}
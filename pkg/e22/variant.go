@@ -0,0 +1,19 @@
+package e22
+
+import "github.com/mbalug7/go-ebyte-lora/pkg/hal"
+
+// Variant describes chip specific register layout that is used by the shared config read logic
+type Variant struct {
+	Name           string
+	ReadableStart  hal.RegAddress // address of the first readable register
+	ReadableLength uint8          // number of readable registers, starting with ReadableStart
+	RegisterCount  uint8          // number of config registers on the chip, including write only registers
+}
+
+// VariantE22 E22 module, first six registers are readable, crypt registers are write only
+var VariantE22 = Variant{
+	Name:           "E22",
+	ReadableStart:  0x00,
+	ReadableLength: 0x06,
+	RegisterCount:  0x08,
+}
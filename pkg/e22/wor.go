@@ -0,0 +1,40 @@
+package e22
+
+import (
+	"fmt"
+
+	"github.com/mbalug7/go-ebyte-lora/pkg/hal"
+)
+
+// WORRole defines wake on receive role of the module, it depends on the chip mode
+type WORRole int
+
+const (
+	// WORRoleNone module is not in a WOR mode
+	WORRoleNone WORRole = iota
+	// WORRoleTransmitter module is in ModeWakeUp, every transmission is prefixed with the wake up preamble
+	WORRoleTransmitter
+	// WORRoleReceiver module is in ModePowerSave, it wakes up every WOR cycle to listen for the preamble
+	WORRoleReceiver
+)
+
+// WORCycleMs returns WOR cycle in milliseconds, as read from the chip
+func (obj *Module) WORCycleMs() int {
+	cycle := obj.currentRegisters()[REG3].(*Reg3).worCycle
+	return (int(cycle) + 1) * 500
+}
+
+// WORRole returns WOR role of the module, that depends on the current chip mode
+func (obj *Module) WORRole() (WORRole, error) {
+	mode, err := obj.hw.GetMode()
+	if err != nil {
+		return WORRoleNone, fmt.Errorf("failed to get chip mode: %w", err)
+	}
+	switch mode {
+	case hal.ModeWakeUp:
+		return WORRoleTransmitter, nil
+	case hal.ModePowerSave:
+		return WORRoleReceiver, nil
+	}
+	return WORRoleNone, nil
+}
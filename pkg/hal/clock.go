@@ -0,0 +1,101 @@
+package hal
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock source of time for timeouts and delays, replace it with FakeClock to test time based logic without waiting
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+	Sleep(d time.Duration)
+}
+
+// RealClock Clock that uses the time package
+type RealClock struct{}
+
+// Now returns current time
+func (RealClock) Now() time.Time {
+	return time.Now()
+}
+
+// After waits for the duration to elapse and then sends the current time on the returned channel
+func (RealClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// Sleep pauses the current goroutine for the duration
+func (RealClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+// fakeClockWaiter channel that is notified when the fake clock reaches the deadline
+type fakeClockWaiter struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+// FakeClock Clock whose time moves only when Advance is called
+type FakeClock struct {
+	now     time.Time
+	waiters []fakeClockWaiter
+	mu      sync.Mutex
+}
+
+// NewFakeClock constructs fake clock that starts at the given time
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{now: start}
+}
+
+// Now returns current fake time
+func (obj *FakeClock) Now() time.Time {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	return obj.now
+}
+
+// After returns channel that receives the fake time once the clock is advanced by d
+func (obj *FakeClock) After(d time.Duration) <-chan time.Time {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- obj.now
+		return ch
+	}
+	obj.waiters = append(obj.waiters, fakeClockWaiter{deadline: obj.now.Add(d), ch: ch})
+	return ch
+}
+
+// Sleep blocks until the clock is advanced by d
+func (obj *FakeClock) Sleep(d time.Duration) {
+	<-obj.After(d)
+}
+
+// Advance moves the fake time forward, and wakes up everything that waits for a deadline that is reached
+func (obj *FakeClock) Advance(d time.Duration) {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	obj.now = obj.now.Add(d)
+	sort.Slice(obj.waiters, func(i, j int) bool {
+		return obj.waiters[i].deadline.Before(obj.waiters[j].deadline)
+	})
+	pending := obj.waiters[:0]
+	for _, w := range obj.waiters {
+		if w.deadline.After(obj.now) {
+			pending = append(pending, w)
+			continue
+		}
+		w.ch <- obj.now
+	}
+	obj.waiters = pending
+}
+
+// Waiters returns number of pending After and Sleep calls, use it to wait until the tested code blocks on the clock
+func (obj *FakeClock) Waiters() int {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	return len(obj.waiters)
+}
@@ -0,0 +1,9 @@
+package hal
+
+import "errors"
+
+// ErrChipBusyTimeout is returned when the module doesn't release AUX line in time
+var ErrChipBusyTimeout = errors.New("chip busy timeout")
+
+// ErrNotSupported is returned when an operation needs an optional HWHandler interface that the handler doesn't implement
+var ErrNotSupported = errors.New("not supported by the hardware handler")
@@ -1,6 +1,11 @@
 package hal
 
-import "github.com/tarm/serial"
+import (
+	"context"
+	"time"
+
+	"github.com/tarm/serial"
+)
 
 // ChipMode defines chip mode type that is used across the lib
 type ChipMode int
@@ -8,6 +13,10 @@ type ChipMode int
 // OnMessageCb registers callback method that is called when a new message is received
 type OnMessageCb func([]byte, error)
 
+// OnAuxEdgeCb registers callback method that is called on AUX line edge while the module is receiving
+// rising edge means that the module is not busy anymore
+type OnAuxEdgeCb func(rising bool, t time.Time)
+
 // chip modes, read module documentation for more info
 const (
 	ModeNormal ChipMode = iota
@@ -16,7 +25,9 @@ const (
 	ModeSleep
 )
 
-// HWHandler interface that defines module handler -> handler that is used to communicate and control eByte lora module
+// HWHandler interface that defines module handler -> handler that is used to communicate and control eByte lora module.
+// Additional capabilities are defined by the optional interfaces below, Module checks for them with type assertions
+// and falls back to the methods of HWHandler, or reports ErrNotSupported when there is no fallback
 type HWHandler interface {
 	ReadSerial() ([]byte, error)
 	WriteSerial(msg []byte) error
@@ -25,3 +36,60 @@ type HWHandler interface {
 	GetMode() (ChipMode, error)
 	RegisterOnMessageCb(OnMessageCb) error
 }
+
+// ContextHandler optional HWHandler interface, writes and mode switches stop waiting for the module when ctx is done.
+// Without it ctx is checked only before the operation starts
+type ContextHandler interface {
+	WriteSerialContext(ctx context.Context, msg []byte) error
+	SetModeContext(ctx context.Context, mode ChipMode) error
+}
+
+// BurstWriter optional HWHandler interface, writes frames back to back without waiting for the module between them.
+// Without it every frame is written with WriteSerial
+type BurstWriter interface {
+	WriteSerialBurst(frames [][]byte) error
+}
+
+// SerialFlusher optional HWHandler interface, drops received data that wasn't read yet.
+// Without it leftovers are not dropped before register commands
+type SerialFlusher interface {
+	FlushSerial() error
+}
+
+// SerialConfigReporter optional HWHandler interface, reports params that are applied to the serial port
+type SerialConfigReporter interface {
+	CurrentSerialConfig() (baudRate int, parityBit serial.Parity)
+}
+
+// StopBitsStager optional HWHandler interface, stages serial stop bits that are applied with the staged port config.
+// Without it the handler keeps its own stop bits
+type StopBitsStager interface {
+	StageSerialStopBits(stopBits serial.StopBits)
+}
+
+// ConfigBaudSetter optional HWHandler interface, sets serial baud rate that is used in ModeSleep
+type ConfigBaudSetter interface {
+	SetConfigModeBaud(baudRate int) error
+}
+
+// AirDataRateSetter optional HWHandler interface, receives air data rate of the module for timeouts that depend on it
+type AirDataRateSetter interface {
+	SetAirDataRate(bitsPerSecond int)
+}
+
+// AUXIdleWaiter optional HWHandler interface, waits until AUX line reports that the module is idle
+type AUXIdleWaiter interface {
+	WaitAUXIdle(timeout time.Duration) error
+}
+
+// AuxEdgeNotifier optional HWHandler interface, reports AUX edges while the module is receiving.
+// Without it Module features that react on AUX edges (power save wake and sleep events) are not available
+type AuxEdgeNotifier interface {
+	RegisterOnAuxEdgeCb(OnAuxEdgeCb) error
+}
+
+// ModeLineDriver optional HWHandler interface, reports whether the handler drives M0 and M1 lines.
+// Handlers that don't implement it are expected to leave the lines untouched
+type ModeLineDriver interface {
+	DrivesModeLines() bool
+}